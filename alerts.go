@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// parseThreshold parses an optional numeric flag; an empty value disables it.
+func parseThreshold(name string, value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return &v, nil
+}
+
+// checkAlerts returns a message for every breached threshold: the latest period's alpha
+// below minAlpha or the tracking error of the trailing year above maxTE. A nil threshold is
+// not checked.
+func checkAlerts(rows []ReportRow, freq Frequency, minAlpha *float64, maxTE *float64) []string {
+	alerts := make([]string, 0)
+	last := rows[len(rows)-1]
+	if minAlpha != nil && last.Alpha < *minAlpha {
+		alerts = append(alerts, fmt.Sprintf("%s alpha %.5f is below %.5f", last.Date, last.Alpha, *minAlpha))
+	}
+	if maxTE != nil {
+		n := min(len(rows), int(freq.PeriodsPerYear))
+		alphas := make([]float64, 0, n)
+		for _, r := range rows[len(rows)-n:] {
+			alphas = append(alphas, r.Alpha)
+		}
+		if te := trackingError(alphas, freq.PeriodsPerYear); te > *maxTE {
+			alerts = append(alerts, fmt.Sprintf("trailing %d-period tracking error %.5f is above %.5f", n, te, *maxTE))
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const alphaVantageURL = "https://www.alphavantage.co/query"
+
+// alphaVantageSeries maps -interval to the Alpha Vantage function and the key of its series
+// in the response. Weekly and monthly bars are dividend adjusted; adjusted daily bars need a
+// premium key, so daily closes are raw.
+var alphaVantageSeries = map[string]struct {
+	Function string
+	Key      string
+}{
+	"1d":  {"TIME_SERIES_DAILY", "Time Series (Daily)"},
+	"1wk": {"TIME_SERIES_WEEKLY_ADJUSTED", "Weekly Adjusted Time Series"},
+	"1mo": {"TIME_SERIES_MONTHLY_ADJUSTED", "Monthly Adjusted Time Series"},
+}
+
+// alphaVantageSuffixes turns Yahoo exchange suffixes into Alpha Vantage ones, so the same
+// symbols work with either provider. The Alpha Vantage suffixes map to themselves.
+var alphaVantageSuffixes = map[string]string{
+	".L":   ".LON",
+	".DE":  ".DEX",
+	".TO":  ".TRT",
+	".V":   ".TRV",
+	".BO":  ".BSE",
+	".SS":  ".SHH",
+	".SZ":  ".SHZ",
+	".LON": ".LON",
+	".DEX": ".DEX",
+	".TRT": ".TRT",
+	".TRV": ".TRV",
+	".BSE": ".BSE",
+	".SHH": ".SHH",
+	".SHZ": ".SHZ",
+}
+
+// alphaVantageProvider reads daily, weekly or monthly bars from Alpha Vantage with Key.
+type alphaVantageProvider struct {
+	Key string
+}
+
+// alphaVantageSymbol maps an exchange suffix to Alpha Vantage's, rejecting suffixes of
+// exchanges Alpha Vantage does not list rather than letting the query come back empty.
+func alphaVantageSymbol(symbol string) (string, error) {
+	dot := strings.LastIndex(symbol, ".")
+	if dot < 0 {
+		return symbol, nil
+	}
+	av, ok := alphaVantageSuffixes[strings.ToUpper(symbol[dot:])]
+	if !ok {
+		return "", fmt.Errorf("history error %s: unsupported exchange suffix %s for alphavantage: %w", symbol, symbol[dot:], ErrSymbolNotFound)
+	}
+	return symbol[:dot] + av, nil
+}
+
+func (p alphaVantageProvider) Fetch(symbol string, start time.Time, end time.Time, interval string) (Series, error) {
+	series, ok := alphaVantageSeries[interval]
+	if !ok {
+		return Series{}, fmt.Errorf("history error %s: alphavantage supports intervals 1d, 1wk and 1mo, not %s", symbol, interval)
+	}
+	avSymbol, err := alphaVantageSymbol(symbol)
+	if err != nil {
+		return Series{}, err
+	}
+	params := url.Values{
+		"function":   {series.Function},
+		"symbol":     {avSymbol},
+		"outputsize": {"full"},
+		"apikey":     {p.Key},
+	}
+	resp, err := yahooHTTP.Get(alphaVantageURL + "?" + params.Encode())
+	if err != nil {
+		return Series{}, fmt.Errorf("history error %s: %w", symbol, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return Series{}, fmt.Errorf("history error %s: status %s", symbol, resp.Status)
+	}
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Series{}, fmt.Errorf("history error %s: %w", symbol, err)
+	}
+	return parseAlphaVantage(symbol, body, series.Key, start, end)
+}
+
+// parseAlphaVantage reads the bars between start and end (zero for the latest) from a
+// decoded response, preferring the adjusted close. Errors are reported in the body with a
+// 200 status: "Error Message" for unknown symbols, "Note" or "Information" for exhausted
+// quotas.
+func parseAlphaVantage(symbol string, body map[string]json.RawMessage, key string, start time.Time, end time.Time) (Series, error) {
+	var msg string
+	if raw, ok := body["Error Message"]; ok {
+		_ = json.Unmarshal(raw, &msg)
+		return Series{}, fmt.Errorf("history error %s: %s: %w", symbol, msg, ErrSymbolNotFound)
+	}
+	for _, k := range []string{"Note", "Information"} {
+		if raw, ok := body[k]; ok {
+			_ = json.Unmarshal(raw, &msg)
+			return Series{}, fmt.Errorf("history error %s: %s: %w", symbol, msg, ErrRateLimited)
+		}
+	}
+	var bars map[string]map[string]string
+	if err := json.Unmarshal(body[key], &bars); err != nil || bars == nil {
+		return Series{}, fmt.Errorf("history error %s: no %q in response", symbol, key)
+	}
+
+	points := make([]PricePoint, 0, len(bars))
+	for dateStr, bar := range bars {
+		d, err := parsePriceDate(dateStr)
+		if err != nil {
+			return Series{}, fmt.Errorf("parse date for %s: %w", symbol, err)
+		}
+		if d.Before(start) || !end.IsZero() && d.After(end) {
+			continue
+		}
+		value, ok := bar["5. adjusted close"]
+		if !ok {
+			value = bar["4. close"]
+		}
+		c, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(c) || c <= 0 {
+			continue
+		}
+		points = append(points, PricePoint{Date: d, Close: c})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Date.Before(points[j].Date) })
+	return Series{Symbol: symbol, Points: points}, nil
+}
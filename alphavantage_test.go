@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAlphaVantageSymbol(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"SPY", "SPY", true},
+		{"VWRL.L", "VWRL.LON", true},
+		{"eunl.de", "eunl.DEX", true},
+		{"VWRL.LON", "VWRL.LON", true},
+		{"SWDA.MI", "", false},
+		{"IWDA.AS", "", false},
+	}
+	for _, tt := range tests {
+		got, err := alphaVantageSymbol(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("alphaVantageSymbol(%q) = %q, %v", tt.in, got, err)
+		}
+		if err != nil && !errors.Is(err, ErrSymbolNotFound) {
+			t.Errorf("alphaVantageSymbol(%q): %v does not wrap ErrSymbolNotFound", tt.in, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// Params are the settings of one ETF vs index analysis.
+type Params struct {
+	ETF        string
+	Index      string
+	StartDate  string
+	EndDate    string // empty when the analysis runs to the latest data
+	Interval   string
+	Freq       Frequency
+	LifeWeight float64
+	Glide      []float64             // ETF weights at evenly spaced points of the period
+	Age        AgeGlide              // replaces Glide when BirthYear is set
+	Tent       BondTent              // adds a BondTent track when Retire is set, starting from Glide's first weight
+	Overrides  map[time.Time]float64 // ETF weights by month, layered on any glide shape
+	Metrics    []Metric
+	MAR        float64 // annual
+}
+
+// Result is everything one analysis produced; every output format reads from it.
+type Result struct {
+	Params Params
+
+	// Aligned per-period returns and the strategies built from them.
+	Dates        []time.Time
+	ETFReturns   []float64
+	IndexReturns []float64
+	LifeReturns  []float64
+	GlideReturns []float64
+	GlideWeights []float64
+	Overridden   int       // periods whose glide weight came from Overrides
+	TentReturns  []float64 // nil without a bond tent
+	TentWeights  []float64
+
+	// Rows holds the periods with a valid alpha.
+	Rows          []ReportRow
+	Alphas        []float64
+	AvgAlpha      float64
+	WinCount      int
+	Total         int
+	FinalETF      float64
+	FinalIndex    float64
+	TrackingError float64
+	Years         []YearRow
+	Metrics       []MetricResult
+	Strategies    []StrategyMetrics
+}
+
+// Analyze builds the strategies, report rows and metrics from aligned ETF and index returns.
+// riskFree holds the per-period rate aligned with dates, or nil when the returns are already
+// in excess of it.
+func Analyze(p Params, dates []time.Time, retsE []float64, retsI []float64, riskFree []float64) (Result, error) {
+	weights, overridden := calendarWeights(dates, p, pathWeights(len(dates), p.Glide))
+	res := Result{
+		Params:       p,
+		Dates:        dates,
+		ETFReturns:   retsE,
+		IndexReturns: retsI,
+		LifeReturns:  blendReturns(retsE, retsI, p.LifeWeight),
+		GlideReturns: weightedReturns(retsE, retsI, weights),
+		GlideWeights: weights,
+		Overridden:   overridden,
+	}
+	if !p.Tent.Retire.IsZero() {
+		res.TentWeights = tentWeights(dates, p.Glide[0], p.Tent)
+		res.TentReturns = weightedReturns(retsE, retsI, res.TentWeights)
+	}
+
+	cumE := cumulative(100, retsE)
+	cumI := cumulative(100, retsI)
+	cumLife := cumulative(100, res.LifeReturns)
+	cumGlide := cumulative(100, res.GlideReturns)
+	glideTraded := glideTurnover(retsE, retsI, res.GlideWeights)
+
+	sumAlpha := 0.0
+	rowRF := make([]float64, 0, len(dates))
+	rowTent := make([]float64, 0, len(dates))
+	res.Rows = make([]ReportRow, 0, len(dates))
+	res.Alphas = make([]float64, 0, len(dates))
+	for i, d := range dates {
+		alpha := retsE[i] - retsI[i]
+		if math.IsNaN(alpha) || math.IsInf(alpha, 0) {
+			continue
+		}
+		res.Total++
+		if alpha > 0 {
+			res.WinCount++
+		}
+		sumAlpha += alpha
+		res.Alphas = append(res.Alphas, alpha)
+		if riskFree != nil {
+			rowRF = append(rowRF, riskFree[i])
+		}
+		if res.TentReturns != nil {
+			rowTent = append(rowTent, res.TentReturns[i])
+		}
+
+		res.Rows = append(res.Rows, ReportRow{
+			Period:      d,
+			Date:        p.Freq.Label(d),
+			ETF:         cumE[i],
+			Index:       cumI[i],
+			Alpha:       alpha,
+			Life:        cumLife[i],
+			Glide:       cumGlide[i],
+			Weight:      res.GlideWeights[i],
+			ETFReturn:   retsE[i],
+			IndexReturn: retsI[i],
+			LifeReturn:  res.LifeReturns[i],
+			GlideReturn: res.GlideReturns[i],
+			GlideTraded: glideTraded[i],
+		})
+	}
+	if res.Total == 0 {
+		return res, ErrAlignment
+	}
+	if riskFree == nil {
+		rowRF = nil
+	}
+
+	res.AvgAlpha = sumAlpha / float64(res.Total)
+	res.FinalETF = cumE[len(cumE)-1]
+	res.FinalIndex = cumI[len(cumI)-1]
+	res.TrackingError = trackingError(res.Alphas, p.Freq.PeriodsPerYear)
+	res.Years = yearlyReturns(dates, retsE, retsI)
+
+	etfRets := make([]float64, len(res.Rows))
+	idxRets := make([]float64, len(res.Rows))
+	for i, r := range res.Rows {
+		etfRets[i] = r.ETFReturn
+		idxRets[i] = r.IndexReturn
+	}
+	mar := periodRate(p.MAR, p.Freq.PeriodsPerYear)
+	res.Metrics = computeMetrics(p.Metrics, MetricInput{
+		Returns:        etfRets,
+		Benchmark:      idxRets,
+		RiskFree:       rowRF,
+		MAR:            mar,
+		PeriodsPerYear: p.Freq.PeriodsPerYear,
+	})
+	res.Strategies = strategyMetrics(p.Metrics, res.Rows, rowRF, mar, p.Freq.PeriodsPerYear)
+	if res.TentReturns != nil {
+		res.Strategies = append(res.Strategies, StrategyMetrics{Name: "BondTent", Results: computeMetrics(p.Metrics, MetricInput{
+			Returns:        rowTent,
+			Benchmark:      idxRets,
+			RiskFree:       rowRF,
+			MAR:            mar,
+			PeriodsPerYear: p.Freq.PeriodsPerYear,
+		})})
+	}
+	return res, nil
+}
+
+// calendarWeights applies the date-driven glide settings of p to the base weights: an age
+// rule replaces them and monthly overrides are layered on top. It returns the weights and
+// how many periods were overridden.
+func calendarWeights(dates []time.Time, p Params, base []float64) ([]float64, int) {
+	weights := base
+	if p.Age.BirthYear != 0 {
+		weights = ageWeights(dates, p.Age)
+	}
+	return weights, applyOverrides(dates, weights, p.Overrides)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// csvTail is what -append needs from an existing CSV: its header and its last row.
+type csvTail struct {
+	Header   []string
+	LastDate string
+	Last     map[string]string // last row values by column name
+}
+
+// parseCSVTail reads the header and last row date of an existing output CSV.
+func parseCSVTail(data []byte) (csvTail, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return csvTail{}, err
+	}
+	var tail csvTail
+	if len(records) == 0 {
+		return tail, nil
+	}
+	tail.Header = records[0]
+	if len(records) > 1 {
+		dateCol := -1
+		for i, name := range tail.Header {
+			if name == "Date" {
+				dateCol = i
+			}
+		}
+		if dateCol < 0 {
+			return csvTail{}, fmt.Errorf("no Date column to append after")
+		}
+		last := records[len(records)-1]
+		tail.LastDate = last[dateCol]
+		tail.Last = make(map[string]string, len(last))
+		for i, name := range tail.Header {
+			if i < len(last) {
+				tail.Last[name] = last[i]
+			}
+		}
+	}
+	return tail, nil
+}
+
+// appendStart returns the index of the first row newer than the existing file's last row,
+// after checking that the file was written with the same columns.
+func appendStart(tail csvTail, rows []ReportRow, columns []csvColumn, dateFormat string) (int, error) {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name
+	}
+	if strings.Join(names, ",") != strings.Join(tail.Header, ",") {
+		return 0, fmt.Errorf("existing header %q does not match columns %q", strings.Join(tail.Header, ","), strings.Join(names, ","))
+	}
+	if tail.LastDate == "" {
+		return 0, nil
+	}
+	for i, r := range rows {
+		date := r.Date
+		if dateFormat != "" {
+			date = r.Period.Format(dateFormat)
+		}
+		if date == tail.LastDate {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("last row %s of the existing file is not in the fetched range", tail.LastDate)
+}
+
+// cumulativeFields are the columns holding cumulative tracks, which -append rebases.
+var cumulativeFields = map[string]func(r *ReportRow) *float64{
+	"ETF":          func(r *ReportRow) *float64 { return &r.ETF },
+	"Index":        func(r *ReportRow) *float64 { return &r.Index },
+	"LifeStrategy": func(r *ReportRow) *float64 { return &r.Life },
+	"GlidePath":    func(r *ReportRow) *float64 { return &r.Glide },
+}
+
+// rebaseRows scales the cumulative tracks of the rows appended after last, this run's row
+// for the existing file's last date, so they continue from the file's last values even when
+// the file was started from another date.
+func rebaseRows(tail csvTail, last ReportRow, rows []ReportRow) ([]ReportRow, error) {
+	_, diff := tail.Last["CumulativeDiff"]
+	_, etf := tail.Last["ETF"]
+	_, index := tail.Last["Index"]
+	if diff && !(etf && index) {
+		return nil, fmt.Errorf("appending CumulativeDiff needs the ETF and Index columns")
+	}
+	out := append([]ReportRow(nil), rows...)
+	for name, field := range cumulativeFields {
+		value, ok := tail.Last[name]
+		if !ok {
+			continue
+		}
+		prev, err := strconv.ParseFloat(value, 64)
+		if err != nil || prev <= 0 {
+			return nil, fmt.Errorf("cannot continue %s from %q in the last row", name, value)
+		}
+		cur := *field(&last)
+		if cur <= 0 {
+			return nil, fmt.Errorf("cannot continue %s from a track at %.2f", name, cur)
+		}
+		for i := range out {
+			*field(&out[i]) *= prev / cur
+		}
+	}
+	return out, nil
+}
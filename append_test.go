@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteCSVOutputAppendRebases(t *testing.T) {
+	fakeEnv(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC))
+	columns, err := parseColumns("Date,ETF,Index,CumulativeDiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The existing file was started earlier, so its tracks stand higher at 2024-02.
+	existing := "Date,ETF,Index,CumulativeDiff\n2024-01,150.00,120.00,30.00\n2024-02,165.00,132.00,33.00\n"
+	if _, err := writeFileAtomic("out.csv", []byte(existing)); err != nil {
+		t.Fatal(err)
+	}
+	rows := []ReportRow{
+		{Date: "2024-02", ETF: 100, Index: 100},
+		{Date: "2024-03", ETF: 110, Index: 105},
+	}
+	if err := writeCSVOutput("out.csv", true, nil, rows, columns, ""); err != nil {
+		t.Fatal(err)
+	}
+	got, err := outputFS.ReadFile("out.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := existing + "2024-03,181.50,138.60,42.90\n"
+	if string(got) != want {
+		t.Errorf("appended file:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Without both tracks the difference cannot be continued.
+	tail, err := parseCSVTail([]byte("Date,CumulativeDiff\n2024-02,33.00\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rebaseRows(tail, rows[0], rows[1:]); err == nil || !strings.Contains(err.Error(), "CumulativeDiff") {
+		t.Errorf("rebaseRows without ETF and Index: err %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"html"
+)
+
+// badgeSVG renders a flat shields-style badge. Widths are estimated from the text length
+// since the viewer's font is unknown.
+func badgeSVG(label string, value string, color string) string {
+	lw, vw := 7*len(label)+10, 7*len([]rune(value))+10
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[3]s: %[4]s">
+<rect width="%[2]d" height="20" fill="#555"/>
+<rect x="%[2]d" width="%[5]d" height="20" fill="%[6]s"/>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="14">%[3]s</text>
+<text x="%[8]d" y="14">%[4]s</text>
+</g>
+</svg>
+`, lw+vw, lw, html.EscapeString(label), html.EscapeString(value), vw, color, lw/2, lw+vw/2)
+}
+
+// alphaBadge shows the average alpha in basis points per period, green when the ETF beat
+// the index on average and red otherwise.
+func alphaBadge(avgAlpha float64, freq Frequency) string {
+	color := "#4c1"
+	if avgAlpha < 0 {
+		color = "#e05d44"
+	}
+	return badgeSVG("avg alpha", fmt.Sprintf("%.1f bps/%s", avgAlpha*10000, freq.Unit), color)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var errCircuitOpen = errors.New("circuit open: provider disabled after repeated failures")
+
+// CircuitBreaker stops calling a provider after threshold consecutive failures. Once
+// cooldown has passed a single probe call is let through; its outcome closes the circuit
+// again or restarts the cooldown.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	name      string
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     string
+	openedAt  time.Time
+}
+
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{name: name, threshold: threshold, cooldown: cooldown, state: "closed"}
+}
+
+func (b *CircuitBreaker) transition(state string) {
+	if b.state != state {
+		fmt.Fprintf(os.Stderr, "Circuit %s: %s -> %s\n", b.name, b.state, state)
+		b.state = state
+	}
+}
+
+// Allow reports whether a call may be made now. A nil breaker or threshold <= 0 always allows.
+func (b *CircuitBreaker) Allow() error {
+	if b == nil || b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case "open":
+		if since(b.openedAt) < b.cooldown {
+			return errCircuitOpen
+		}
+		b.transition("half-open")
+		return nil
+	case "half-open":
+		// Only the probe already in flight may call.
+		return errCircuitOpen
+	}
+	return nil
+}
+
+// Record updates the breaker with the outcome of a call let through by Allow.
+func (b *CircuitBreaker) Record(err error) {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.failures = 0
+		b.transition("closed")
+		return
+	}
+	b.failures++
+	if b.state == "half-open" || b.failures >= b.threshold {
+		b.openedAt = clock.Now()
+		b.transition("open")
+	}
+}
+
+// providerBreaker guards every call made to the price provider during the run.
+var providerBreaker *CircuitBreaker
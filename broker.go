@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// brokerFormat describes a broker's trade export by its header names. Rows before the header
+// (account details some brokers prepend) are skipped.
+type brokerFormat struct {
+	Comma       rune
+	Decimal     rune // decimal separator of numbers, 0 when the export's locale varies
+	DateLayouts []string
+	Date        string
+	Symbol      string
+	Quantity    string
+	Price       string   // empty when the price is derived from Amount
+	Amount      string   // total value of the trade, used when Price is empty
+	Fees        []string // alternative names across export versions; values may be negative
+	Side        string   // column telling buys from sells when quantities are unsigned
+	Buys        []string
+	Sells       []string
+}
+
+// brokerFormats are the exports accepted by -broker.
+var brokerFormats = map[string]brokerFormat{
+	"degiro": {
+		Comma:       ',',
+		DateLayouts: []string{"02-01-2006"},
+		Date:        "Date",
+		Symbol:      "ISIN",
+		Quantity:    "Quantity",
+		Price:       "Price",
+		Fees:        []string{"Transaction and/or third party fees", "Transaction costs"},
+	},
+	"ibkr": {
+		Comma:       ',',
+		Decimal:     '.',
+		DateLayouts: []string{"20060102", "2006-01-02"},
+		Date:        "TradeDate",
+		Symbol:      "Symbol",
+		Quantity:    "Quantity",
+		Price:       "TradePrice",
+		Fees:        []string{"IBCommission"},
+	},
+	"directa": {
+		Comma:       ';',
+		Decimal:     ',',
+		DateLayouts: []string{"02-01-2006", "02/01/2006"},
+		Date:        "Data operazione",
+		Symbol:      "Ticker",
+		Quantity:    "Quantità",
+		Amount:      "Importo euro",
+		Side:        "Tipo operazione",
+		Buys:        []string{"Acquisto"},
+		Sells:       []string{"Vendita"},
+	},
+}
+
+func brokerNames() string {
+	names := make([]string, 0, len(brokerFormats))
+	for name := range brokerFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// loadBrokerTransactions reads a broker export in the named format.
+func loadBrokerTransactions(path string, broker string) ([]Transaction, error) {
+	format, ok := brokerFormats[strings.ToLower(broker)]
+	if !ok {
+		return nil, fmt.Errorf("unknown broker %q (want %s)", broker, brokerNames())
+	}
+	f, err := outputFS.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open transactions: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return parseBrokerTransactions(f, format)
+}
+
+func parseBrokerTransactions(r io.Reader, format brokerFormat) ([]Transaction, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = format.Comma
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	var cols map[string]int
+	out := make([]Transaction, 0)
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read transactions: %w", err)
+		}
+		if cols == nil {
+			cols = headerColumns(rec)
+			if _, ok := cols[strings.ToLower(format.Date)]; !ok {
+				cols = nil
+			}
+			continue
+		}
+		field := func(name string) string {
+			if i, ok := cols[strings.ToLower(name)]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		number := func(name string) (float64, error) {
+			v := field(name)
+			if v == "" {
+				return 0, nil
+			}
+			n, err := parseLocaleNumber(v, format.Decimal)
+			if err != nil {
+				return 0, fmt.Errorf("transactions line %d: invalid %s: %w", line, name, err)
+			}
+			return n, nil
+		}
+		if field(format.Date) == "" {
+			continue
+		}
+		if format.Side != "" && !containsFold(format.Buys, field(format.Side)) && !containsFold(format.Sells, field(format.Side)) {
+			continue
+		}
+		d, err := parseDateLayouts(field(format.Date), format.DateLayouts)
+		if err != nil {
+			return nil, fmt.Errorf("transactions line %d: invalid date %q", line, field(format.Date))
+		}
+		tx := Transaction{Date: d, Symbol: field(format.Symbol)}
+		if tx.Quantity, err = number(format.Quantity); err != nil {
+			return nil, err
+		}
+		if tx.Quantity == 0 {
+			continue
+		}
+		if format.Price != "" {
+			if tx.Price, err = number(format.Price); err != nil {
+				return nil, err
+			}
+		} else {
+			amount, err := number(format.Amount)
+			if err != nil {
+				return nil, err
+			}
+			tx.Price = math.Abs(amount / tx.Quantity)
+		}
+		for _, name := range format.Fees {
+			fee, err := number(name)
+			if err != nil {
+				return nil, err
+			}
+			tx.Fees += math.Abs(fee)
+		}
+		if containsFold(format.Sells, field(format.Side)) {
+			tx.Quantity = -math.Abs(tx.Quantity)
+		}
+		out = append(out, tx)
+	}
+	if cols == nil {
+		return nil, fmt.Errorf("transactions: no %q header found", format.Date)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("transactions: no trades")
+	}
+	return out, nil
+}
+
+// headerColumns maps lower-cased header names to their column, ignoring a UTF-8 BOM.
+func headerColumns(rec []string) map[string]int {
+	cols := make(map[string]int, len(rec))
+	for i, name := range rec {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")
+		if _, ok := cols[strings.ToLower(name)]; !ok {
+			cols[strings.ToLower(name)] = i
+		}
+	}
+	return cols
+}
+
+func parseDateLayouts(value string, layouts []string) (time.Time, error) {
+	var err error
+	for _, layout := range layouts {
+		var d time.Time
+		if d, err = time.Parse(layout, value); err == nil {
+			return d, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// parseLocaleNumber parses a number whose decimal separator is decimal, '.' or ','; the other
+// one groups thousands. With decimal 0 the last separator is the decimal one, and a lone
+// separator followed by exactly three digits, as in 1,000, is rejected as ambiguous.
+func parseLocaleNumber(value string, decimal rune) (float64, error) {
+	dot, comma := strings.LastIndex(value, "."), strings.LastIndex(value, ",")
+	if decimal == 0 {
+		last := max(dot, comma)
+		if last >= 0 && len(value)-last-1 == 3 && strings.Count(value, ".")+strings.Count(value, ",") == 1 {
+			return 0, fmt.Errorf("ambiguous number %q: the decimal separator is unknown", value)
+		}
+		decimal = '.'
+		if comma > dot {
+			decimal = ','
+		}
+	}
+	clean := value
+	if decimal == ',' {
+		clean = strings.ReplaceAll(clean, ".", "")
+		clean = strings.Replace(clean, ",", ".", 1)
+	} else {
+		clean = strings.ReplaceAll(clean, ",", "")
+	}
+	n, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return n, nil
+}
+
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// mapSymbols renames traded symbols, e.g. ISINs from a broker export to Yahoo symbols, using
+// FROM=TO pairs separated by commas.
+func mapSymbols(txs []Transaction, pairs string) error {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(pairs, ",") {
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+			return fmt.Errorf("invalid symbol mapping %q (want FROM=TO)", pair)
+		}
+		mapping[strings.TrimSpace(from)] = strings.TrimSpace(to)
+	}
+	for i := range txs {
+		if to, ok := mapping[txs[i].Symbol]; ok {
+			txs[i].Symbol = to
+		}
+	}
+	return nil
+}
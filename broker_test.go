@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestParseLocaleNumber(t *testing.T) {
+	tests := []struct {
+		value   string
+		decimal rune
+		want    float64
+		ok      bool
+	}{
+		{"1,234.56", 0, 1234.56, true},
+		{"1.234,56", 0, 1234.56, true},
+		{"12.5", 0, 12.5, true},
+		{"-3,75", 0, -3.75, true},
+		{"1,000.5", 0, 1000.5, true},
+		{"1,000", 0, 0, false},
+		{"1.000", 0, 0, false},
+		{"1,000", '.', 1000, true},
+		{"1,000", ',', 1, true},
+		{"1.000", ',', 1000, true},
+		{"1.234.567,8", ',', 1234567.8, true},
+		{"abc", '.', 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseLocaleNumber(tt.value, tt.decimal)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseLocaleNumber(%q, %q) = %v, %v", tt.value, tt.decimal, got, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// HistoryCache keeps downloaded price history on disk as one JSON file per symbol and
+// interval, so repeated runs do not download it again while it is younger than TTL and only
+// fetch the new bars once it is older.
+type HistoryCache struct {
+	Dir string
+	TTL time.Duration
+}
+
+// cachedHistory is the file format of a cache entry.
+type cachedHistory struct {
+	Symbol   string
+	Interval string
+	Start    string // query start (YYYY-MM-DD) the points cover
+	End      string // query end (YYYY-MM-DD), empty for the latest data
+	Fetched  time.Time
+	Points   []PricePoint
+}
+
+// historyCache is the cache used by loadPrices; nil disables caching.
+var historyCache *HistoryCache
+
+// defaultCacheDir is yahoo_finance_ae under the user cache directory (~/.cache on Linux).
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "yahoo_finance_ae")
+}
+
+func (c *HistoryCache) path(symbol string, interval string) string {
+	clean := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, symbol)
+	return filepath.Join(c.Dir, clean+"-"+interval+".json")
+}
+
+// Lookup returns the cache entry for the query when it covers the query's start and has the
+// same end, however old it is.
+func (c *HistoryCache) Lookup(symbol string, query PriceQuery) (cachedHistory, bool) {
+	data, err := outputFS.ReadFile(c.path(symbol, query.Interval))
+	if err != nil {
+		return cachedHistory{}, false
+	}
+	var entry cachedHistory
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return cachedHistory{}, false
+	}
+	if entry.Start > query.Start.Format("2006-01-02") || entry.End != cacheEnd(query) {
+		return cachedHistory{}, false
+	}
+	return entry, true
+}
+
+// cacheEnd is the query end as stored in cache entries.
+func cacheEnd(query PriceQuery) string {
+	if query.End.IsZero() {
+		return ""
+	}
+	return query.End.Format("2006-01-02")
+}
+
+// Series returns the cached points from start on.
+func (e cachedHistory) Series(start time.Time) Series {
+	points := make([]PricePoint, 0, len(e.Points))
+	for _, p := range e.Points {
+		if !p.Date.Before(start) {
+			points = append(points, p)
+		}
+	}
+	return Series{Symbol: e.Symbol, Points: points}
+}
+
+// mergePoints replaces the cached points from the first fetched bar on with the fetched ones.
+func mergePoints(cached []PricePoint, fetched []PricePoint) []PricePoint {
+	if len(fetched) == 0 {
+		return cached
+	}
+	out := make([]PricePoint, 0, len(cached)+len(fetched))
+	for _, p := range cached {
+		if p.Date.Before(fetched[0].Date) {
+			out = append(out, p)
+		}
+	}
+	return append(out, fetched...)
+}
+
+// Store saves the history downloaded for the query, warning on stderr when it cannot.
+func (c *HistoryCache) Store(query PriceQuery, series Series) {
+	if err := c.store(query, series); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+func (c *HistoryCache) store(query PriceQuery, series Series) error {
+	if err := outputFS.MkdirAll(c.Dir, 0o755); err != nil {
+		return fmt.Errorf("cache dir: %w", err)
+	}
+	data, err := json.Marshal(cachedHistory{
+		Symbol:   series.Symbol,
+		Interval: query.Interval,
+		Start:    query.Start.Format("2006-01-02"),
+		End:      cacheEnd(query),
+		Fetched:  clock.Now(),
+		Points:   series.Points,
+	})
+	if err != nil {
+		return fmt.Errorf("cache %s: %w", series.Symbol, err)
+	}
+	if _, err := writeFileAtomic(c.path(series.Symbol, query.Interval), data); err != nil {
+		return fmt.Errorf("cache %s: %w", series.Symbol, err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// stubProvider serves fixed daily bars, or fails with err, and records the start of every
+// fetch.
+type stubProvider struct {
+	points []PricePoint
+	starts []time.Time
+	err    error
+}
+
+func (p *stubProvider) Fetch(symbol string, start time.Time, end time.Time, interval string) (Series, error) {
+	p.starts = append(p.starts, start)
+	if p.err != nil {
+		return Series{}, p.err
+	}
+	out := make([]PricePoint, 0, len(p.points))
+	for _, pt := range p.points {
+		if !pt.Date.Before(start) {
+			out = append(out, pt)
+		}
+	}
+	return Series{Symbol: symbol, Points: out}, nil
+}
+
+func TestLoadPricesCache(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+	c, m := fakeEnv(t, day(3, 1))
+	stub := &stubProvider{points: []PricePoint{{day(1, 2), 10}, {day(2, 1), 11}}}
+	oldProvider, oldCache := priceProvider, historyCache
+	priceProvider = stub
+	historyCache = &HistoryCache{Dir: "cache", TTL: time.Hour}
+	t.Cleanup(func() { priceProvider, historyCache = oldProvider, oldCache })
+	query := PriceQuery{Start: day(1, 1), Interval: "1d"}
+
+	load := func(symbol string) Series {
+		t.Helper()
+		s, err := loadPrices(symbol, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	load("SPY")
+	if len(stub.starts) != 1 || len(m.files) != 1 {
+		t.Fatalf("first load: %d fetches, %d cache files", len(stub.starts), len(m.files))
+	}
+
+	// Within the TTL the cache answers.
+	c.now = c.now.Add(30 * time.Minute)
+	if s := load("SPY"); len(s.Points) != 2 || len(stub.starts) != 1 {
+		t.Fatalf("fresh cache: %d points after %d fetches", len(s.Points), len(stub.starts))
+	}
+
+	// Once stale, only the bars from the last cached one on are fetched.
+	stub.points = append(stub.points, PricePoint{day(3, 1), 12})
+	c.now = c.now.Add(2 * time.Hour)
+	if s := load("SPY"); len(s.Points) != 3 || !stub.starts[1].Equal(day(2, 1)) {
+		t.Fatalf("stale cache: %d points, fetch from %v", len(s.Points), stub.starts[1])
+	}
+
+	// An empty entry is never fresh.
+	stub.points = nil
+	load("NEW")
+	stub.points = []PricePoint{{day(3, 1), 5}}
+	load("NEW")
+	if len(stub.starts) != 4 || !stub.starts[3].Equal(day(1, 1)) {
+		t.Errorf("empty entry: fetches from %v, want a full refetch", stub.starts)
+	}
+}
+
+func TestLoadPricesFallback(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+	c, m := fakeEnv(t, day(3, 1))
+	primary := &stubProvider{err: errors.New("status 503")}
+	backup := &stubProvider{points: []PricePoint{{day(1, 2), 10}}}
+	oldProvider, oldFallback, oldBreaker, oldCache := priceProvider, fallbackProvider, providerBreaker, historyCache
+	priceProvider, fallbackProvider = primary, backup
+	providerBreaker = NewCircuitBreaker("stub", 1, time.Minute)
+	historyCache = &HistoryCache{Dir: "cache", TTL: time.Hour}
+	t.Cleanup(func() {
+		priceProvider, fallbackProvider, providerBreaker, historyCache = oldProvider, oldFallback, oldBreaker, oldCache
+	})
+	query := PriceQuery{Start: day(1, 1), Interval: "1d"}
+
+	// The failure opens the circuit but is reported: only an open circuit falls back.
+	if _, err := loadPrices("SPY", query); err == nil || len(backup.starts) != 0 {
+		t.Fatalf("failing provider: err %v, %d fallback fetches", err, len(backup.starts))
+	}
+	s, err := loadPrices("SPY", query)
+	if err != nil || len(s.Points) != 1 || len(primary.starts) != 1 || len(backup.starts) != 1 {
+		t.Fatalf("open circuit: %d points, err %v, %d main and %d fallback fetches", len(s.Points), err, len(primary.starts), len(backup.starts))
+	}
+	if len(m.files) != 0 {
+		t.Errorf("fallback bars cached: %q", m.files)
+	}
+
+	// After the cooldown the main provider is probed again.
+	c.now = c.now.Add(2 * time.Minute)
+	primary.err = nil
+	primary.points = backup.points
+	if _, err := loadPrices("SPY", query); err != nil || len(primary.starts) != 2 {
+		t.Errorf("probe: err %v, %d main fetches", err, len(primary.starts))
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExchangeCalendar knows the full-day closures of one exchange.
+type ExchangeCalendar struct {
+	Name     string
+	holidays func(year int) []time.Time
+	cache    map[int]map[time.Time]bool
+}
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// easterSunday uses the anonymous Gregorian algorithm.
+func easterSunday(y int) time.Time {
+	a := y % 19
+	b := y / 100
+	c := y % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	dd := (h+l-7*m+114)%31 + 1
+	return day(y, time.Month(month), dd)
+}
+
+// nthWeekday returns the nth (1-based) weekday of a month; n < 0 counts from the end.
+func nthWeekday(y int, m time.Month, wd time.Weekday, n int) time.Time {
+	if n > 0 {
+		d := day(y, m, 1)
+		offset := (int(wd) - int(d.Weekday()) + 7) % 7
+		return d.AddDate(0, 0, offset+7*(n-1))
+	}
+	d := day(y, m+1, 1).AddDate(0, 0, -1)
+	offset := (int(d.Weekday()) - int(wd) + 7) % 7
+	return d.AddDate(0, 0, -offset-7*(-n-1))
+}
+
+// usObserved moves Saturday holidays to Friday and Sunday holidays to Monday.
+func usObserved(d time.Time) time.Time {
+	switch d.Weekday() {
+	case time.Saturday:
+		return d.AddDate(0, 0, -1)
+	case time.Sunday:
+		return d.AddDate(0, 0, 1)
+	}
+	return d
+}
+
+func nyseHolidays(y int) []time.Time {
+	easter := easterSunday(y)
+	out := []time.Time{
+		nthWeekday(y, time.January, time.Monday, 3),
+		nthWeekday(y, time.February, time.Monday, 3),
+		easter.AddDate(0, 0, -2),
+		nthWeekday(y, time.May, time.Monday, -1),
+		usObserved(day(y, time.July, 4)),
+		nthWeekday(y, time.September, time.Monday, 1),
+		nthWeekday(y, time.November, time.Thursday, 4),
+		usObserved(day(y, time.December, 25)),
+	}
+	// NYSE does not close on the Friday before a Saturday New Year's Day.
+	if ny := day(y, time.January, 1); ny.Weekday() != time.Saturday {
+		out = append(out, usObserved(ny))
+	}
+	if y >= 2022 {
+		out = append(out, usObserved(day(y, time.June, 19)))
+	}
+	return out
+}
+
+func lseHolidays(y int) []time.Time {
+	easter := easterSunday(y)
+	ny := day(y, time.January, 1)
+	for ny.Weekday() == time.Saturday || ny.Weekday() == time.Sunday {
+		ny = ny.AddDate(0, 0, 1)
+	}
+	// Christmas and Boxing Day move to the next free weekdays.
+	xmas := day(y, time.December, 25)
+	boxing := day(y, time.December, 26)
+	switch xmas.Weekday() {
+	case time.Friday:
+		boxing = day(y, time.December, 28)
+	case time.Saturday:
+		xmas, boxing = day(y, time.December, 27), day(y, time.December, 28)
+	case time.Sunday:
+		xmas = day(y, time.December, 27)
+	}
+	return []time.Time{
+		ny,
+		easter.AddDate(0, 0, -2),
+		easter.AddDate(0, 0, 1),
+		nthWeekday(y, time.May, time.Monday, 1),
+		nthWeekday(y, time.May, time.Monday, -1),
+		nthWeekday(y, time.August, time.Monday, -1),
+		xmas,
+		boxing,
+	}
+}
+
+func xetraHolidays(y int) []time.Time {
+	easter := easterSunday(y)
+	return []time.Time{
+		day(y, time.January, 1),
+		easter.AddDate(0, 0, -2),
+		easter.AddDate(0, 0, 1),
+		day(y, time.May, 1),
+		day(y, time.December, 24),
+		day(y, time.December, 25),
+		day(y, time.December, 26),
+		day(y, time.December, 31),
+	}
+}
+
+func borsaItalianaHolidays(y int) []time.Time {
+	return append(xetraHolidays(y), day(y, time.August, 15))
+}
+
+var exchangeCalendars = map[string]*ExchangeCalendar{
+	"NYSE":  {Name: "NYSE", holidays: nyseHolidays},
+	"LSE":   {Name: "LSE", holidays: lseHolidays},
+	"MTA":   {Name: "Borsa Italiana", holidays: borsaItalianaHolidays},
+	"XETRA": {Name: "Xetra", holidays: xetraHolidays},
+}
+
+// calendarSuffixes maps Yahoo symbol suffixes to exchange calendars.
+var calendarSuffixes = map[string]string{
+	".MI": "MTA",
+	".L":  "LSE",
+	".DE": "XETRA",
+	".F":  "XETRA",
+}
+
+// calendarFor resolves an -*-exchange flag value. auto guesses from the symbol suffix
+// (no suffix means a US listing); indices and unknown suffixes return nil.
+func calendarFor(name string, symbol string) (*ExchangeCalendar, error) {
+	switch strings.ToUpper(name) {
+	case "", "AUTO":
+		if strings.HasPrefix(symbol, "^") {
+			return nil, nil
+		}
+		dot := strings.LastIndex(symbol, ".")
+		if dot < 0 {
+			return exchangeCalendars["NYSE"], nil
+		}
+		return exchangeCalendars[calendarSuffixes[strings.ToUpper(symbol[dot:])]], nil
+	case "NONE":
+		return nil, nil
+	}
+	cal, ok := exchangeCalendars[strings.ToUpper(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown exchange %q (want auto, none, NYSE, LSE, MTA or XETRA)", name)
+	}
+	return cal, nil
+}
+
+// IsOpen reports whether the exchange trades on the given day.
+func (c *ExchangeCalendar) IsOpen(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	if c.cache == nil {
+		c.cache = make(map[int]map[time.Time]bool)
+	}
+	y := t.Year()
+	closed, ok := c.cache[y]
+	if !ok {
+		closed = make(map[time.Time]bool)
+		for _, h := range c.holidays(y) {
+			closed[h] = true
+		}
+		c.cache[y] = closed
+	}
+	return !closed[dailyFrequency.Key(t)]
+}
+
+// CalendarCheck summarizes how a daily series matches its exchange calendar.
+type CalendarCheck struct {
+	Expected   int
+	Missing    []time.Time
+	ClosedBars int
+}
+
+// checkCalendar compares the bars of a daily series with the trading days of cal between
+// its first and last bar.
+func checkCalendar(s Series, cal *ExchangeCalendar) CalendarCheck {
+	var res CalendarCheck
+	if len(s.Points) == 0 {
+		return res
+	}
+	have := make(map[time.Time]bool, len(s.Points))
+	for _, p := range s.Points {
+		d := dailyFrequency.Key(p.Date)
+		have[d] = true
+		if !cal.IsOpen(d) {
+			res.ClosedBars++
+		}
+	}
+	first := dailyFrequency.Key(s.Points[0].Date)
+	last := dailyFrequency.Key(s.Points[len(s.Points)-1].Date)
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		if !cal.IsOpen(d) {
+			continue
+		}
+		res.Expected++
+		if !have[d] {
+			res.Missing = append(res.Missing, d)
+		}
+	}
+	return res
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ChartOptions controls the dimensions, palette and series of the HTML report charts.
+type ChartOptions struct {
+	CumHeight   int
+	AlphaHeight int
+	Series      []string
+	Colors      map[string]string
+	AlphaStyle  string
+	Events      []ChartEvent
+	Lines       []ChartLine
+	YieldLines  []ChartLine
+	StyleLines  []ChartLine
+	Excess      bool
+	RealLines   []ChartLine
+	RealTable   []RealReturn
+	Savings     []SavingsRow
+	SavingsGoal float64
+	Lifecycle   *LifecycleReport
+	Appendix    string
+	Alerts      []string
+	Regimes     []ChangePoint
+	Overlay     []OverlayLine
+	OverlayName string
+	Pair        *PairedETF
+	Sweep       []SweepPoint
+	Frontier    []SweepPoint
+	Marks       []FrontierMark
+}
+
+// linePalette colors extra comparison lines such as peer ETFs, cycling when exhausted.
+var linePalette = []string{"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+// chartSeries lists the cumulative series in plotting order with the JS array holding their data.
+var chartSeries = []struct {
+	Name string
+	Var  string
+}{
+	{"ETF", "etfData"},
+	{"Index", "indexData"},
+	{"LifeStrategy", "lifeData"},
+	{"GlidePath", "glideData"},
+}
+
+func defaultChartOptions() ChartOptions {
+	return ChartOptions{
+		CumHeight:   120,
+		AlphaHeight: 90,
+		Series:      []string{"ETF", "Index", "LifeStrategy", "GlidePath"},
+		Colors: map[string]string{
+			"ETF":          "#1f77b4",
+			"Index":        "#ff7f0e",
+			"LifeStrategy": "#2ca02c",
+			"GlidePath":    "#9467bd",
+			"Alpha":        "#dc3545",
+		},
+		AlphaStyle: "bar",
+	}
+}
+
+// parseChartSeries validates a comma separated list of series names.
+func parseChartSeries(value string) ([]string, error) {
+	out := make([]string, 0, len(chartSeries))
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, s := range chartSeries {
+			if strings.EqualFold(s.Name, name) {
+				out = append(out, s.Name)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown chart series %q", name)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("at least one chart series is required")
+	}
+	return out, nil
+}
+
+// parseChartColors merges Name=#rrggbb pairs into the palette.
+func parseChartColors(value string, colors map[string]string) error {
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, color, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid chart color %q (want Name=#rrggbb)", pair)
+		}
+		if _, _, _, err := hexRGB(color); err != nil {
+			return err
+		}
+		key := ""
+		for k := range colors {
+			if strings.EqualFold(k, name) {
+				key = k
+			}
+		}
+		if key == "" {
+			return fmt.Errorf("unknown chart color target %q", name)
+		}
+		colors[key] = color
+	}
+	return nil
+}
+
+func hexRGB(color string) (int64, int64, int64, error) {
+	if len(color) != 7 || color[0] != '#' {
+		return 0, 0, 0, fmt.Errorf("invalid color %q (want #rrggbb)", color)
+	}
+	v, err := strconv.ParseInt(color[1:], 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid color %q (want #rrggbb)", color)
+	}
+	return v >> 16, (v >> 8) & 0xff, v & 0xff, nil
+}
+
+// fillColor returns the translucent variant of a #rrggbb color used for chart fills.
+func fillColor(color string, alpha float64) string {
+	r, g, b, err := hexRGB(color)
+	if err != nil {
+		return color
+	}
+	return fmt.Sprintf("rgba(%d,%d,%d,%.2f)", r, g, b, alpha)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// Clock supplies the current time so time-dependent behaviour such as staleness checks,
+// breaker cooldowns and rate limiting can run against a fixed time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// clock is the time source used throughout the tool.
+var clock Clock = systemClock{}
+
+// since is time.Since on clock.
+func since(t time.Time) time.Duration {
+	return clock.Now().Sub(t)
+}
+
+// TempFile is the part of *os.File that writeFileAtomic needs.
+type TempFile interface {
+	Name() string
+	Write(p []byte) (int, error)
+	Sync() error
+	Close() error
+}
+
+// FileSystem is the file access used to read inputs, cache history and write outputs, so all
+// of them can run against an in-memory implementation.
+type FileSystem interface {
+	Open(name string) (io.ReadCloser, error)
+	Create(name string) (io.WriteCloser, error)
+	Stat(name string) (os.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	CreateTemp(dir string, pattern string) (TempFile, error)
+	Chmod(name string, mode os.FileMode) error
+	Rename(oldpath string, newpath string) error
+	Remove(name string) error
+	MkdirAll(path string, perm os.FileMode) error
+}
+
+type osFS struct{}
+
+func (osFS) Open(name string) (io.ReadCloser, error)    { return os.Open(name) }
+func (osFS) Create(name string) (io.WriteCloser, error) { return os.Create(name) }
+func (osFS) Stat(name string) (os.FileInfo, error)      { return os.Stat(name) }
+func (osFS) ReadFile(name string) ([]byte, error)       { return os.ReadFile(name) }
+func (osFS) CreateTemp(dir string, pattern string) (TempFile, error) {
+	return os.CreateTemp(dir, pattern)
+}
+func (osFS) Chmod(name string, mode os.FileMode) error   { return os.Chmod(name, mode) }
+func (osFS) Rename(oldpath string, newpath string) error { return os.Rename(oldpath, newpath) }
+func (osFS) Remove(name string) error                    { return os.Remove(name) }
+func (osFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// outputFS is the file system inputs are read from and outputs are written to.
+var outputFS FileSystem = osFS{}
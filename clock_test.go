@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fixedClock is a Clock that only moves when the test advances it.
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time { return c.now }
+
+// memFS is an in-memory FileSystem keyed by cleaned path.
+type memFS struct {
+	files map[string][]byte
+	temps int
+}
+
+func newMemFS() *memFS { return &memFS{files: make(map[string][]byte)} }
+
+// memFile buffers writes and stores them in its memFS on Close.
+type memFile struct {
+	fs   *memFS
+	name string
+	buf  bytes.Buffer
+}
+
+func (f *memFile) Name() string                { return f.name }
+func (f *memFile) Write(p []byte) (int, error) { return f.buf.Write(p) }
+func (f *memFile) Sync() error                 { return nil }
+func (f *memFile) Close() error {
+	f.fs.files[f.name] = bytes.Clone(f.buf.Bytes())
+	return nil
+}
+
+type memInfo struct {
+	name string
+	size int64
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) Mode() fs.FileMode  { return 0o644 }
+func (i memInfo) ModTime() time.Time { return time.Time{} }
+func (i memInfo) IsDir() bool        { return false }
+func (i memInfo) Sys() any           { return nil }
+
+func (m *memFS) Open(name string) (io.ReadCloser, error) {
+	data, err := m.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memFS) Create(name string) (io.WriteCloser, error) {
+	return &memFile{fs: m, name: filepath.Clean(name)}, nil
+}
+
+func (m *memFS) Stat(name string) (os.FileInfo, error) {
+	data, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return memInfo{name: filepath.Base(name), size: int64(len(data))}, nil
+}
+
+func (m *memFS) ReadFile(name string) ([]byte, error) {
+	data, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return data, nil
+}
+
+func (m *memFS) CreateTemp(dir string, pattern string) (TempFile, error) {
+	m.temps++
+	return &memFile{fs: m, name: filepath.Join(dir, fmt.Sprintf("%s%d", pattern, m.temps))}, nil
+}
+
+func (m *memFS) Chmod(name string, mode os.FileMode) error { return nil }
+
+func (m *memFS) Rename(oldpath string, newpath string) error {
+	data, ok := m.files[oldpath]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: oldpath, Err: fs.ErrNotExist}
+	}
+	delete(m.files, oldpath)
+	m.files[filepath.Clean(newpath)] = data
+	return nil
+}
+
+func (m *memFS) Remove(name string) error {
+	delete(m.files, filepath.Clean(name))
+	return nil
+}
+
+func (m *memFS) MkdirAll(path string, perm os.FileMode) error { return nil }
+
+// fakeEnv swaps in a fixed clock and an in-memory file system for the test.
+func fakeEnv(t *testing.T, now time.Time) (*fixedClock, *memFS) {
+	t.Helper()
+	c, m := &fixedClock{now: now}, newMemFS()
+	oldClock, oldFS := clock, outputFS
+	clock, outputFS = c, m
+	t.Cleanup(func() { clock, outputFS = oldClock, oldFS })
+	return c, m
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	_, m := fakeEnv(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	changed, err := writeFileAtomic("out/report.csv", []byte("a,b\n"))
+	if err != nil || !changed {
+		t.Fatalf("first write: changed %v, err %v", changed, err)
+	}
+	changed, err = writeFileAtomic("out/report.csv", []byte("a,b\n"))
+	if err != nil || changed {
+		t.Fatalf("identical write: changed %v, err %v", changed, err)
+	}
+	if len(m.files) != 1 || string(m.files["out/report.csv"]) != "a,b\n" {
+		t.Errorf("files = %q, want only out/report.csv", m.files)
+	}
+}
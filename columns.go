@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvColumn is one selectable output column.
+type csvColumn struct {
+	Name   string
+	Format func(r ReportRow) string
+}
+
+// csvColumns lists every column -columns can select; the first seven form the default schema.
+var csvColumns = []csvColumn{
+	{"Date", func(r ReportRow) string { return r.Date }},
+	{"ETF", func(r ReportRow) string { return fmt.Sprintf("%.2f", r.ETF) }},
+	{"Index", func(r ReportRow) string { return fmt.Sprintf("%.2f", r.Index) }},
+	{"Alpha", func(r ReportRow) string { return fmt.Sprintf("%.5f", r.Alpha) }},
+	{"LifeStrategy", func(r ReportRow) string { return fmt.Sprintf("%.2f", r.Life) }},
+	{"GlidePath", func(r ReportRow) string { return fmt.Sprintf("%.2f", r.Glide) }},
+	{"GlideEtfWeight", func(r ReportRow) string { return fmt.Sprintf("%.4f", r.Weight) }},
+	{"ETFReturn", func(r ReportRow) string { return fmt.Sprintf("%.5f", r.ETFReturn) }},
+	{"IndexReturn", func(r ReportRow) string { return fmt.Sprintf("%.5f", r.IndexReturn) }},
+	{"CumulativeDiff", func(r ReportRow) string { return fmt.Sprintf("%.2f", r.ETF-r.Index) }},
+	{"GlideTraded", func(r ReportRow) string { return fmt.Sprintf("%.2f", r.GlideTraded) }},
+}
+
+const defaultColumns = "Date,ETF,Index,Alpha,LifeStrategy,GlidePath,GlideEtfWeight"
+
+// parseColumns resolves a comma separated column list, keeping the requested order.
+func parseColumns(value string) ([]csvColumn, error) {
+	out := make([]csvColumn, 0)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, c := range csvColumns {
+			if strings.EqualFold(c.Name, name) {
+				out = append(out, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			names := make([]string, len(csvColumns))
+			for i, c := range csvColumns {
+				names[i] = c.Name
+			}
+			return nil, fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(names, ","))
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("at least one column is required")
+	}
+	return out, nil
+}
+
+// writeCSV writes the selected columns, preceded by a header line when header is set. A
+// non-empty dateFormat is a Go time layout applied to the period start in place of the
+// default Date label.
+func writeCSV(w io.Writer, rows []ReportRow, columns []csvColumn, dateFormat string, header bool) {
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+
+	if header {
+		names := make([]string, len(columns))
+		for i, c := range columns {
+			names[i] = c.Name
+		}
+		_ = cw.Write(names)
+	}
+
+	fields := make([]string, len(columns))
+	for _, r := range rows {
+		if dateFormat != "" {
+			r.Date = r.Period.Format(dateFormat)
+		}
+		for i, c := range columns {
+			fields[i] = c.Format(r)
+		}
+		_ = cw.Write(fields)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// isCompressedPath reports whether writes to path are compressed.
+func isCompressedPath(path string) bool {
+	p := strings.ToLower(path)
+	return strings.HasSuffix(p, ".gz") || strings.HasSuffix(p, ".zst")
+}
+
+// compressedWriter wraps w with gzip or zstd compression chosen from the path extension
+// (.gz, .zst). Other paths are written as is. Close flushes the compressor but not w.
+func compressedWriter(path string, w io.Writer) (io.WriteCloser, error) {
+	p := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(p, ".gz"):
+		return gzip.NewWriter(w), nil
+	case strings.HasSuffix(p, ".zst"):
+		return zstd.NewWriter(w)
+	}
+	return nopWriteCloser{w}, nil
+}
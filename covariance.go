@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+)
+
+// covarianceMatrix returns the sample covariance of every pair of equally long return
+// columns.
+func covarianceMatrix(cols [][]float64) [][]float64 {
+	means := make([]float64, len(cols))
+	for j, c := range cols {
+		for _, v := range c {
+			means[j] += v
+		}
+		means[j] /= float64(len(c))
+	}
+	cov := make([][]float64, len(cols))
+	for a := range cols {
+		cov[a] = make([]float64, len(cols))
+		for b := range cols {
+			s := 0.0
+			for i := range cols[a] {
+				s += (cols[a][i] - means[a]) * (cols[b][i] - means[b])
+			}
+			cov[a][b] = s / float64(len(cols[a])-1)
+		}
+	}
+	return cov
+}
+
+// correlationMatrix scales a covariance matrix to correlations; columns without variance
+// correlate 0 with everything else.
+func correlationMatrix(cov [][]float64) [][]float64 {
+	corr := make([][]float64, len(cov))
+	for a := range cov {
+		corr[a] = make([]float64, len(cov))
+		for b := range cov {
+			if d := math.Sqrt(cov[a][a] * cov[b][b]); d > 0 {
+				corr[a][b] = cov[a][b] / d
+			}
+		}
+	}
+	return corr
+}
+
+// renderCovariance writes the covariance and correlation matrices of the per-period returns
+// as CSV: a header of matrix,symbol and the symbols, then one row per symbol and matrix.
+func renderCovariance(symbols []string, cols [][]float64) []byte {
+	var b bytes.Buffer
+	cw := csv.NewWriter(&b)
+	_ = cw.Write(append([]string{"matrix", "symbol"}, symbols...))
+	cov := covarianceMatrix(cols)
+	for _, m := range []struct {
+		name   string
+		values [][]float64
+		format string
+	}{{"covariance", cov, "%.8g"}, {"correlation", correlationMatrix(cov), "%.4f"}} {
+		for a, sym := range symbols {
+			rec := []string{m.name, sym}
+			for _, v := range m.values[a] {
+				rec = append(rec, fmt.Sprintf(m.format, v))
+			}
+			_ = cw.Write(rec)
+		}
+	}
+	cw.Flush()
+	return b.Bytes()
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	fredGraphURL = "https://fred.stlouisfed.org/graph/fredgraph.csv"
+	eurostatURL  = "https://ec.europa.eu/eurostat/api/dissemination/statistics/1.0/data/prc_hicp_midx"
+)
+
+// loadCPI resolves -cpi into monthly price index levels keyed by the first of the month:
+// EUCPI is the euro area HICP from Eurostat, csv:path reads a local date,value file and any
+// other value is taken as a FRED series id such as CPIAUCSL.
+func loadCPI(source string) (map[time.Time]float64, error) {
+	switch {
+	case strings.EqualFold(source, "EUCPI"):
+		return fetchEurostatHICP()
+	case strings.HasPrefix(source, "csv:"):
+		f, err := outputFS.Open(strings.TrimPrefix(source, "csv:"))
+		if err != nil {
+			return nil, fmt.Errorf("open cpi: %w", err)
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		return parseCPICSV(f)
+	}
+	body, err := getCPI(fredGraphURL + "?" + url.Values{"id": {source}}.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("fred %s: %w", source, err)
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	return parseCPICSV(body)
+}
+
+func getCPI(endpoint string) (io.ReadCloser, error) {
+	resp, err := yahooHTTP.Get(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// parseCPICSV reads date,value rows; dates are YYYY-MM-DD or YYYY-MM, a header row is skipped
+// and FRED's "." placeholders for missing observations are ignored.
+func parseCPICSV(r io.Reader) (map[time.Time]float64, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	out := make(map[time.Time]float64)
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read cpi: %w", err)
+		}
+		if len(rec) < 2 {
+			continue
+		}
+		d, err := time.Parse("2006-01-02", rec[0])
+		if err != nil {
+			d, err = time.Parse("2006-01", rec[0])
+		}
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("cpi line %d: invalid date %q", line, rec[0])
+		}
+		if rec[1] == "." || rec[1] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(rec[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("cpi line %d: invalid value %q", line, rec[1])
+		}
+		out[monthlyFrequency.Key(d)] = v
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("cpi: no observations")
+	}
+	return out, nil
+}
+
+// eurostatResponse is the part of the JSON-stat dataset used here. Every dimension other
+// than time is fixed by the query, so value positions are the time positions.
+type eurostatResponse struct {
+	Value     map[string]float64 `json:"value"`
+	Dimension struct {
+		Time struct {
+			Category struct {
+				Index map[string]int `json:"index"`
+			} `json:"category"`
+		} `json:"time"`
+	} `json:"dimension"`
+}
+
+func fetchEurostatHICP() (map[time.Time]float64, error) {
+	params := url.Values{"geo": {"EA"}, "coicop": {"CP00"}, "unit": {"I15"}}
+	body, err := getCPI(eurostatURL + "?" + params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("eurostat hicp: %w", err)
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	var res eurostatResponse
+	if err := json.NewDecoder(body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("eurostat hicp: %w", err)
+	}
+	out := make(map[time.Time]float64, len(res.Value))
+	for period, pos := range res.Dimension.Time.Category.Index {
+		d, err := time.Parse("2006-01", period)
+		if err != nil {
+			continue
+		}
+		if v, ok := res.Value[strconv.Itoa(pos)]; ok {
+			out[d] = v
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("eurostat hicp: no observations")
+	}
+	return out, nil
+}
+
+// monthlyInflation converts index levels into month-over-month inflation.
+func monthlyInflation(levels map[time.Time]float64) map[time.Time]float64 {
+	months := make([]time.Time, 0, len(levels))
+	for d := range levels {
+		months = append(months, d)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+	out := make(map[time.Time]float64, len(months))
+	for i := 1; i < len(months); i++ {
+		if months[i-1].AddDate(0, 1, 0).Equal(months[i]) {
+			out[months[i]] = levels[months[i]]/levels[months[i-1]] - 1
+		}
+	}
+	return out
+}
+
+// deflateReturns turns nominal returns into real ones. Periods shorter than a month use the
+// inflation of their month spread evenly; periods without CPI data are left nominal and
+// counted in missing.
+func deflateReturns(dates []time.Time, returns []float64, inflation map[time.Time]float64, freq Frequency) (missing int) {
+	for i, d := range dates {
+		m, ok := inflation[monthlyFrequency.Key(d)]
+		if !ok {
+			missing++
+			continue
+		}
+		returns[i] = (1+returns[i])/math.Pow(1+m, 12/freq.PeriodsPerYear) - 1
+	}
+	return missing
+}
+
+// RealReturn compares the annualized nominal and real return of one strategy.
+type RealReturn struct {
+	Name    string
+	Nominal float64
+	Real    float64
+}
+
+// realSection builds the real cumulative line and the annualized nominal/real returns of each
+// strategy; nominal and real hold per-period returns in chartSeries order.
+func realSection(dates []time.Time, nominal [][]float64, real [][]float64, freq Frequency) ([]ChartLine, []RealReturn) {
+	lines := make([]ChartLine, len(chartSeries))
+	table := make([]RealReturn, len(chartSeries))
+	for j, cs := range chartSeries {
+		lines[j] = ChartLine{Label: cs.Name + " (real)", Values: make(map[string]float64, len(dates))}
+		for i, v := range cumulative(100, real[j]) {
+			lines[j].Values[freq.Label(dates[i])] = v
+		}
+		table[j] = RealReturn{
+			Name:    cs.Name,
+			Nominal: annualizedReturn(nominal[j], freq.PeriodsPerYear),
+			Real:    annualizedReturn(real[j], freq.PeriodsPerYear),
+		}
+	}
+	return lines, table
+}
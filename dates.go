@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// priceDateLayouts are tried in order by parsePriceDate for the textual dates returned by
+// the different Yahoo endpoints and providers.
+var priceDateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -0700 MST",
+	"2006-01-02 15:04:05 MST",
+}
+
+// parsePriceDate accepts ISO dates, timestamps with or without zone suffixes and epoch
+// seconds (or milliseconds). Zone-suffixed values keep their wall-clock time, relabelled as
+// UTC, so a bar stays on the trading day of its exchange.
+func parsePriceDate(value string) (time.Time, error) {
+	v := strings.TrimSpace(value)
+	if v != "" && strings.Trim(v, "0123456789") == "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err == nil {
+			if n > 1e11 {
+				return time.UnixMilli(n).UTC(), nil
+			}
+			return time.Unix(n, 0).UTC(), nil
+		}
+	}
+	for _, layout := range priceDateLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q (want YYYY-MM-DD, RFC3339, a zone-suffixed timestamp or epoch seconds)", value)
+}
+
+// periodStart turns a -period preset into the start date that many years before end: 1y,
+// 3y, 5y or 10y, or max for all available history.
+func periodStart(preset string, end time.Time) (string, error) {
+	switch preset {
+	case "max":
+		// Yahoo accepts dates before 1970 and returns whatever history it has.
+		return "1900-01-01", nil
+	case "1y", "3y", "5y", "10y":
+		years, _ := strconv.Atoi(strings.TrimSuffix(preset, "y"))
+		return end.AddDate(-years, 0, 0).Format("2006-01-02"), nil
+	}
+	return "", fmt.Errorf("invalid period %q (want 1y, 3y, 5y, 10y or max)", preset)
+}
+
+// supportedIntervals are the bar sizes the analysis has a Frequency for. Yahoo also serves
+// intraday, 5d and 3mo bars, but they would be annualized and labelled as months.
+var supportedIntervals = []string{"1d", "1wk", "1mo"}
+
+var intervalSynonyms = map[string]string{
+	"daily":   "1d",
+	"day":     "1d",
+	"weekly":  "1wk",
+	"week":    "1wk",
+	"1w":      "1wk",
+	"monthly": "1mo",
+	"month":   "1mo",
+}
+
+// normalizeInterval maps synonyms such as daily or weekly to Yahoo interval codes and
+// rejects intervals the analysis does not support.
+func normalizeInterval(value string) (string, error) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	if alias, ok := intervalSynonyms[v]; ok {
+		v = alias
+	}
+	for _, iv := range supportedIntervals {
+		if v == iv {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported interval %q (want one of %s)", value, strings.Join(supportedIntervals, ","))
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestNormalizeInterval(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"1d", "1d", true},
+		{"Weekly", "1wk", true},
+		{"month", "1mo", true},
+		{"1h", "", false},
+		{"5d", "", false},
+		{"3mo", "", false},
+	}
+	for _, tt := range tests {
+		got, err := normalizeInterval(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("normalizeInterval(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
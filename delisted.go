@@ -0,0 +1,32 @@
+package main
+
+import (
+	"time"
+)
+
+// staleAfter is how old the last bar of a series may be before the symbol is treated as
+// no longer trading (delisted or merged).
+func staleAfter(interval string) time.Duration {
+	switch interval {
+	case "1mo":
+		return 45 * 24 * time.Hour
+	}
+	return 10 * 24 * time.Hour
+}
+
+// seriesRange returns the first and last bar dates of a non-empty series.
+func seriesRange(s Series) (time.Time, time.Time) {
+	return s.Points[0].Date, s.Points[len(s.Points)-1].Date
+}
+
+// truncateSeries keeps the bars between from and to inclusive.
+func truncateSeries(s Series, from time.Time, to time.Time) Series {
+	out := make([]PricePoint, 0, len(s.Points))
+	for _, p := range s.Points {
+		if p.Date.Before(from) || p.Date.After(to) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return Series{Symbol: s.Symbol, Points: out}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// rowVariables are the per-period values a -derive expression can refer to.
+var rowVariables = map[string]func(r ReportRow) float64{
+	"etf":          func(r ReportRow) float64 { return r.ETF },
+	"index":        func(r ReportRow) float64 { return r.Index },
+	"alpha":        func(r ReportRow) float64 { return r.Alpha },
+	"lifestrategy": func(r ReportRow) float64 { return r.Life },
+	"glidepath":    func(r ReportRow) float64 { return r.Glide },
+	"weight":       func(r ReportRow) float64 { return r.Weight },
+	"etfreturn":    func(r ReportRow) float64 { return r.ETFReturn },
+	"indexreturn":  func(r ReportRow) float64 { return r.IndexReturn },
+}
+
+// expr is a compiled arithmetic expression over rowVariables.
+type expr func(r ReportRow) float64
+
+// DerivedSeries is a named expression evaluated for every report row.
+type DerivedSeries struct {
+	Name string
+	Eval expr
+}
+
+// parseDerived parses -derive, a semicolon separated list of name=expression pairs such as
+// "spread=ETF-Index;ratio=ETF/Index*100".
+func parseDerived(value string) ([]DerivedSeries, error) {
+	out := make([]DerivedSeries, 0)
+	for _, part := range strings.Split(value, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, src, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid derived series %q (want name=expression)", part)
+		}
+		e, err := compileExpr(src)
+		if err != nil {
+			return nil, fmt.Errorf("derived series %s: %w", name, err)
+		}
+		out = append(out, DerivedSeries{Name: name, Eval: e})
+	}
+	return out, nil
+}
+
+// derivedColumn exports a derived series as an extra CSV column.
+func derivedColumn(d DerivedSeries) csvColumn {
+	return csvColumn{d.Name, func(r ReportRow) string { return fmt.Sprintf("%.5f", d.Eval(r)) }}
+}
+
+// derivedLine plots a derived series on the cumulative chart.
+func derivedLine(d DerivedSeries, rows []ReportRow) ChartLine {
+	values := make(map[string]float64, len(rows))
+	for _, r := range rows {
+		if v := d.Eval(r); !math.IsNaN(v) && !math.IsInf(v, 0) {
+			values[r.Date] = v
+		}
+	}
+	return ChartLine{Label: d.Name, Values: values}
+}
+
+// exprParser is a recursive descent parser for + - * / with parentheses and unary minus.
+type exprParser struct {
+	src string
+	pos int
+}
+
+func compileExpr(src string) (expr, error) {
+	p := &exprParser{src: src}
+	e, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.src[p.pos:], p.pos)
+	}
+	return e, nil
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *exprParser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *exprParser) sum() (expr, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == '+' {
+			left = func(r ReportRow) float64 { return l(r) + right(r) }
+		} else {
+			left = func(r ReportRow) float64 { return l(r) - right(r) }
+		}
+	}
+}
+
+func (p *exprParser) product() (expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == '*' {
+			left = func(r ReportRow) float64 { return l(r) * right(r) }
+		} else {
+			left = func(r ReportRow) float64 { return l(r) / right(r) }
+		}
+	}
+}
+
+func (p *exprParser) unary() (expr, error) {
+	if p.peek() == '-' {
+		p.pos++
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(r ReportRow) float64 { return -e(r) }, nil
+	}
+	return p.operand()
+}
+
+func (p *exprParser) operand() (expr, error) {
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.pos++
+		e, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ) at offset %d", p.pos)
+		}
+		p.pos++
+		return e, nil
+	case c == '.' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos < len(p.src) && (p.src[p.pos] == '.' || (p.src[p.pos] >= '0' && p.src[p.pos] <= '9')) {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", p.src[start:p.pos])
+		}
+		return func(ReportRow) float64 { return v }, nil
+	case unicode.IsLetter(rune(c)):
+		start := p.pos
+		for p.pos < len(p.src) && (unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+		name := p.src[start:p.pos]
+		get, ok := rowVariables[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown variable %q", name)
+		}
+		return expr(get), nil
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", c, p.pos)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+type Dividend struct {
+	Date   time.Time
+	Amount float64
+}
+
+type chartEventsResponse struct {
+	Chart struct {
+		Result []struct {
+			Events struct {
+				Dividends map[string]struct {
+					Amount float64 `json:"amount"`
+					Date   int64   `json:"date"`
+				} `json:"dividends"`
+			} `json:"events"`
+		} `json:"result"`
+	} `json:"chart"`
+}
+
+// fetchDividends returns the distributions paid by symbol from startDate to endDate
+// (YYYY-MM-DD, empty for today), sorted by ex-date. Symbols that never paid return an empty
+// slice.
+func fetchDividends(symbol string, startDate string, endDate string) ([]Dividend, error) {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return nil, fmt.Errorf("dividends start date: %w", err)
+	}
+	params := url.Values{}
+	params.Set("period1", fmt.Sprintf("%d", start.Unix()))
+	end := clock.Now()
+	if endDate != "" {
+		if end, err = time.Parse("2006-01-02", endDate); err != nil {
+			return nil, fmt.Errorf("dividends end date: %w", err)
+		}
+		end = end.AddDate(0, 0, 1)
+	}
+	params.Set("period2", fmt.Sprintf("%d", end.Unix()))
+	params.Set("interval", "1d")
+	params.Set("events", "div")
+
+	var data chartEventsResponse
+	if err := getYahooJSON("/v8/finance/chart/"+url.PathEscape(symbol), params, &data); err != nil {
+		return nil, fmt.Errorf("dividends %s: %w", symbol, err)
+	}
+
+	divs := make([]Dividend, 0)
+	for _, r := range data.Chart.Result {
+		for _, d := range r.Events.Dividends {
+			if d.Amount <= 0 {
+				continue
+			}
+			divs = append(divs, Dividend{Date: time.Unix(d.Date, 0).UTC(), Amount: d.Amount})
+		}
+	}
+	sort.Slice(divs, func(i, j int) bool { return divs[i].Date.Before(divs[j].Date) })
+	return divs, nil
+}
+
+// periodDividends sums distributions by the period of their ex-date, keyed like periodSeries.
+func periodDividends(divs []Dividend, freq Frequency) map[time.Time]float64 {
+	m := make(map[time.Time]float64)
+	for _, d := range divs {
+		m[freq.Key(d.Date)] += d.Amount
+	}
+	return m
+}
+
+// trailingYield returns the trailing twelve month distribution yield (in percent) at each
+// date, using the period-end close of that date. Dates within the first year are skipped
+// because their window would reach before the fetched history.
+func trailingYield(label string, dates []time.Time, closes map[time.Time]float64, divs map[time.Time]float64, freq Frequency) ChartLine {
+	line := ChartLine{Label: label, Values: make(map[string]float64, len(dates))}
+	if len(dates) == 0 {
+		return line
+	}
+	first := dates[0].AddDate(0, 11, 0)
+	for _, d := range dates {
+		if d.Before(first) {
+			continue
+		}
+		price := closes[d]
+		if price <= 0 {
+			continue
+		}
+		from := d.AddDate(-1, 0, 0)
+		sum := 0.0
+		for k, amount := range divs {
+			if k.After(from) && !k.After(d) {
+				sum += amount
+			}
+		}
+		line.Values[freq.Label(d)] = sum / price * 100
+	}
+	return line
+}
@@ -0,0 +1,31 @@
+package main
+
+import "errors"
+
+// Error categories returned by the data layer. Callers match them with errors.Is to choose
+// exit codes and retry behaviour.
+var (
+	ErrSymbolNotFound = errors.New("symbol not found")
+	ErrNoData         = errors.New("no data")
+	ErrRateLimited    = errors.New("rate limited")
+	ErrAlignment      = errors.New("no aligned periods")
+	ErrAlert          = errors.New("alert threshold breached")
+)
+
+// exitCode maps an error to the process exit status: 3 unknown symbol, 4 no data,
+// 5 rate limited, 6 nothing to compare, 7 alert threshold breached and 1 for anything else.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, ErrSymbolNotFound):
+		return 3
+	case errors.Is(err, ErrNoData):
+		return 4
+	case errors.Is(err, ErrRateLimited):
+		return 5
+	case errors.Is(err, ErrAlignment):
+		return 6
+	case errors.Is(err, ErrAlert):
+		return 7
+	}
+	return 1
+}
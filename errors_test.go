@@ -0,0 +1,26 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("history error X: %w", ErrSymbolNotFound), 3},
+		{ErrNoData, 4},
+		{fmt.Errorf("history error X: quota: %w", ErrRateLimited), 5},
+		{ErrAlignment, 6},
+		{ErrAlert, 7},
+		{errors.New("boom"), 1},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
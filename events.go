@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ChartEvent is a user supplied annotation drawn on the cumulative chart.
+type ChartEvent struct {
+	Date  time.Time
+	Label string
+}
+
+// loadEvents reads a date,label CSV. Dates may be YYYY-MM-DD or YYYY-MM and a header
+// row is skipped when its first field is not a date.
+func loadEvents(path string) ([]ChartEvent, error) {
+	f, err := outputFS.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open events: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	events := make([]ChartEvent, 0)
+	for line := 1; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read events: %w", err)
+		}
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("events line %d: want date,label", line)
+		}
+		dateStr := strings.TrimSpace(rec[0])
+		d, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			d, err = time.Parse("2006-01", dateStr)
+		}
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("events line %d: invalid date %q", line, dateStr)
+		}
+		events = append(events, ChartEvent{Date: d.UTC(), Label: strings.TrimSpace(strings.Join(rec[1:], ","))})
+	}
+	return events, nil
+}
+
+// eventsByLabel groups events under the chart label (month or week) they fall into and reports
+// how many events are outside the plotted range.
+func eventsByLabel(events []ChartEvent, rows []ReportRow, freq Frequency) (map[string][]string, int) {
+	labels := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		labels[r.Date] = true
+	}
+	out := make(map[string][]string)
+	skipped := 0
+	for _, e := range events {
+		key := freq.Label(freq.Key(e.Date))
+		if !labels[key] {
+			skipped++
+			continue
+		}
+		out[key] = append(out[key], e.Label)
+	}
+	return out, skipped
+}
+
+// eventsJS returns the JavaScript defining the events map, the vertical marker plugin and
+// the tooltip callback used by the cumulative chart.
+func eventsJS(byLabel map[string][]string) (string, error) {
+	data, err := json.Marshal(byLabel)
+	if err != nil {
+		return "", fmt.Errorf("encode events: %w", err)
+	}
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "const events = %s;\n", data)
+	b.WriteString("const eventLines = {id:'eventLines',afterDatasetsDraw(c){const x=c.scales.x,a=c.chartArea,ctx=c.ctx;ctx.save();ctx.strokeStyle='#555';ctx.fillStyle='#555';ctx.font='11px Arial';ctx.setLineDash([4,4]);")
+	b.WriteString("for(const [k,v] of Object.entries(events)){const i=c.data.labels.indexOf(k);if(i<0)continue;const px=x.getPixelForValue(i);ctx.beginPath();ctx.moveTo(px,a.top);ctx.lineTo(px,a.bottom);ctx.stroke();ctx.fillText(v.join(', '),px+4,a.top+12);}ctx.restore();}};\n")
+	b.WriteString("const eventTooltip = items => items.length ? (events[items[0].label] || []) : [];\n")
+	return b.String(), nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FactorSet holds factor returns as fractions, keyed by period date.
+type FactorSet struct {
+	Names  []string
+	Values map[time.Time][]float64
+	Daily  bool // YYYYMMDD rows rather than YYYYMM
+}
+
+// loadFactors reads a Fama-French or AQR style factor CSV: free text, a header row whose first
+// field is blank or a date caption followed by the factor names, then YYYYMM or YYYYMMDD rows
+// in percent. Only the first table is read, so the annual section of Ken French files is
+// ignored. An RF column is kept as a factor named RF.
+func loadFactors(path string) (FactorSet, error) {
+	f, err := outputFS.Open(path)
+	if err != nil {
+		return FactorSet{}, fmt.Errorf("open factors: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return parseFactors(f)
+}
+
+func parseFactors(r io.Reader) (FactorSet, error) {
+	set := FactorSet{Values: make(map[time.Time][]float64)}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Split(sc.Text(), ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		if set.Names == nil {
+			if len(fields) > 1 && parseFactorDate(fields[0]).IsZero() && fields[1] != "" {
+				set.Names = fields[1:]
+			}
+			continue
+		}
+		d := parseFactorDate(fields[0])
+		if d.IsZero() || len(fields) != len(set.Names)+1 {
+			if len(set.Values) > 0 {
+				break
+			}
+			continue
+		}
+		daily := len(fields[0]) == len("20060102")
+		if len(set.Values) == 0 {
+			set.Daily = daily
+		} else if daily != set.Daily {
+			return FactorSet{}, fmt.Errorf("factors %s: mixes YYYYMM and YYYYMMDD rows", fields[0])
+		}
+		row := make([]float64, len(set.Names))
+		for i, s := range fields[1:] {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return FactorSet{}, fmt.Errorf("factors %s: invalid value %q", fields[0], s)
+			}
+			row[i] = v / 100
+		}
+		set.Values[d] = row
+	}
+	if err := sc.Err(); err != nil {
+		return FactorSet{}, fmt.Errorf("read factors: %w", err)
+	}
+	if len(set.Values) == 0 {
+		return FactorSet{}, fmt.Errorf("factors: no YYYYMM or YYYYMMDD rows found")
+	}
+	return set, nil
+}
+
+func parseFactorDate(s string) time.Time {
+	for _, layout := range []string{"200601", "20060102"} {
+		if len(s) != len(layout) {
+			continue
+		}
+		if d, err := time.Parse(layout, s); err == nil {
+			return d
+		}
+	}
+	return time.Time{}
+}
+
+// FactorFit is an OLS regression of alpha on factor returns.
+type FactorFit struct {
+	Names     []string
+	Intercept float64
+	Betas     []float64
+	TStats    []float64 // intercept first
+	R2        float64
+	N         int
+}
+
+// regressFactors fits y = a + X b by ordinary least squares; xs holds one column per factor.
+func regressFactors(y []float64, xs [][]float64) (FactorFit, error) {
+	n, k := len(y), len(xs)+1
+	if n <= k {
+		return FactorFit{}, fmt.Errorf("need more than %d periods for %d factors, have %d", k, k-1, n)
+	}
+	col := func(j, t int) float64 {
+		if j == 0 {
+			return 1
+		}
+		return xs[j-1][t]
+	}
+	// Normal equations [X'X | X'y], extended with the identity to get (X'X)^-1 for the
+	// standard errors.
+	m := make([][]float64, k)
+	for i := range m {
+		m[i] = make([]float64, 2*k+1)
+		for j := 0; j < k; j++ {
+			for t := 0; t < n; t++ {
+				m[i][j] += col(i, t) * col(j, t)
+			}
+		}
+		for t := 0; t < n; t++ {
+			m[i][k] += col(i, t) * y[t]
+		}
+		m[i][k+1+i] = 1
+	}
+	if err := solveLinear(m, 1e-12); err != nil {
+		return FactorFit{}, fmt.Errorf("factors are collinear")
+	}
+	coef := make([]float64, k)
+	for i := range coef {
+		coef[i] = m[i][k]
+	}
+	ssRes, ssTot, meanY := 0.0, 0.0, mean(y)
+	for t := 0; t < n; t++ {
+		fit := 0.0
+		for j := 0; j < k; j++ {
+			fit += coef[j] * col(j, t)
+		}
+		ssRes += (y[t] - fit) * (y[t] - fit)
+		ssTot += (y[t] - meanY) * (y[t] - meanY)
+	}
+	sigma2 := ssRes / float64(n-k)
+	tstats := make([]float64, k)
+	for j := range tstats {
+		tstats[j] = coef[j] / math.Sqrt(sigma2*m[j][k+1+j])
+	}
+	fit := FactorFit{Intercept: coef[0], Betas: coef[1:], TStats: tstats, N: n}
+	if ssTot > 0 {
+		fit.R2 = 1 - ssRes/ssTot
+	}
+	return fit, nil
+}
+
+// checkFactorFrequency rejects monthly factors for a weekly or daily analysis, where each
+// period would be paired with the whole month's factor returns.
+func checkFactorFrequency(set FactorSet, freq Frequency) error {
+	if !set.Daily && freq.Name != monthlyFrequency.Name {
+		return fmt.Errorf("factors: monthly rows are coarser than the %s periods; use daily factors or -frequency monthly", freq.Name)
+	}
+	return nil
+}
+
+// alignFactors pairs each aligned period with its factor returns, keyed by freq. Daily rows
+// are compounded within each period in date order.
+func alignFactors(dates []time.Time, alphas []float64, set FactorSet, freq Frequency) ([]float64, [][]float64) {
+	days := make([]time.Time, 0, len(set.Values))
+	for d := range set.Values {
+		days = append(days, d)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	byKey := make(map[time.Time][]float64, len(set.Values))
+	for _, d := range days {
+		key := freq.Key(d)
+		acc, ok := byKey[key]
+		if !ok {
+			acc = make([]float64, len(set.Names))
+			byKey[key] = acc
+		}
+		for j, f := range set.Values[d] {
+			acc[j] = (1+acc[j])*(1+f) - 1
+		}
+	}
+	y := make([]float64, 0, len(dates))
+	xs := make([][]float64, len(set.Names))
+	for i, d := range dates {
+		row, ok := byKey[freq.Key(d)]
+		if !ok || math.IsNaN(alphas[i]) {
+			continue
+		}
+		y = append(y, alphas[i])
+		for j := range xs {
+			xs[j] = append(xs[j], row[j])
+		}
+	}
+	return y, xs
+}
+
+func writeFactorReport(w io.Writer, fit FactorFit, freq Frequency) {
+	fmt.Fprintf(w, "Factor regression of alpha over %d periods (R2 %.3f)\n", fit.N, fit.R2)
+	fmt.Fprintf(w, "  %-10s %10.5f  t=%6.2f  (%.2f%% per year replication error)\n", "Intercept", fit.Intercept, fit.TStats[0], fit.Intercept*freq.PeriodsPerYear*100)
+	for j, name := range fit.Names {
+		fmt.Fprintf(w, "  %-10s %10.5f  t=%6.2f\n", name, fit.Betas[j], fit.TStats[j+1])
+	}
+}
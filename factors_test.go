@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlignDailyFactorsMonthly(t *testing.T) {
+	set, err := parseFactors(strings.NewReader(`Daily factors
+,Mkt-RF,SMB
+20240102,1.00,0.50
+20240103,2.00,-0.50
+20240131,-1.00,0.00
+20240201,0.50,1.00
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !set.Daily {
+		t.Fatal("YYYYMMDD rows not read as daily")
+	}
+	if err := checkFactorFrequency(set, monthlyFrequency); err != nil {
+		t.Fatal(err)
+	}
+	dates := []time.Time{
+		time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+	}
+	for run := 0; run < 10; run++ {
+		y, xs := alignFactors(dates, []float64{0.001, 0.002}, set, monthlyFrequency)
+		if len(y) != 2 {
+			t.Fatalf("%d periods aligned, want 2", len(y))
+		}
+		wantJan := []float64{1.01*1.02*0.99 - 1, 1.005*0.995*1.00 - 1}
+		wantFeb := []float64{0.005, 0.01}
+		for j := range xs {
+			if math.Abs(xs[j][0]-wantJan[j]) > 1e-12 || math.Abs(xs[j][1]-wantFeb[j]) > 1e-12 {
+				t.Fatalf("factor %s = %v, want [%v %v]", set.Names[j], xs[j], wantJan[j], wantFeb[j])
+			}
+		}
+	}
+}
+
+func TestCheckFactorFrequency(t *testing.T) {
+	monthly := FactorSet{Names: []string{"Mkt-RF"}, Values: map[time.Time][]float64{{}: {0.01}}}
+	if err := checkFactorFrequency(monthly, monthlyFrequency); err != nil {
+		t.Errorf("monthly factors, monthly run: %v", err)
+	}
+	for _, freq := range []Frequency{weeklyFrequency, dailyFrequency} {
+		if err := checkFactorFrequency(monthly, freq); err == nil {
+			t.Errorf("monthly factors accepted for a %s run", freq.Name)
+		}
+	}
+	if _, err := parseFactors(strings.NewReader(",Mkt-RF\n202401,1.0\n20240201,1.0\n")); err == nil {
+		t.Error("mixed YYYYMM and YYYYMMDD rows accepted")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// FillStats counts the days filled into one series, split by whether its exchange calendar
+// says the market was closed (a holiday) or open (missing data). Without a calendar every
+// filled day counts as missing.
+type FillStats struct {
+	Closed  int
+	Missing int
+}
+
+// fillGaps aligns two daily series on the union of their trading days. A close missing
+// from one series (typically a holiday on only one exchange) is carried forward or linearly
+// interpolated when the run of missing days is at most maxGap. It returns the filled series
+// and what was added to each.
+func fillGaps(a Series, b Series, calA *ExchangeCalendar, calB *ExchangeCalendar, mode string, maxGap int) (Series, Series, FillStats, FillStats) {
+	days := make(map[time.Time]bool)
+	for _, p := range a.Points {
+		days[dailyFrequency.Key(p.Date)] = true
+	}
+	for _, p := range b.Points {
+		days[dailyFrequency.Key(p.Date)] = true
+	}
+	union := make([]time.Time, 0, len(days))
+	for d := range days {
+		union = append(union, d)
+	}
+	sort.Slice(union, func(i, j int) bool { return union[i].Before(union[j]) })
+
+	fa, na := fillSeries(a, union, calA, mode, maxGap)
+	fb, nb := fillSeries(b, union, calB, mode, maxGap)
+	return fa, fb, na, nb
+}
+
+func fillSeries(s Series, days []time.Time, cal *ExchangeCalendar, mode string, maxGap int) (Series, FillStats) {
+	closes := make(map[time.Time]float64, len(s.Points))
+	for _, p := range s.Points {
+		closes[dailyFrequency.Key(p.Date)] = p.Close
+	}
+
+	out := make([]PricePoint, 0, len(days))
+	var stats FillStats
+	for i := 0; i < len(days); {
+		if c, ok := closes[days[i]]; ok {
+			out = append(out, PricePoint{Date: days[i], Close: c})
+			i++
+			continue
+		}
+		// days[i:j] is a run of missing days.
+		j := i
+		for j < len(days) {
+			if _, ok := closes[days[j]]; ok {
+				break
+			}
+			j++
+		}
+		if i > 0 && j < len(days) && j-i <= maxGap {
+			prev := closes[days[i-1]]
+			next := closes[days[j]]
+			for k := i; k < j; k++ {
+				c := prev
+				if mode == "interpolate" {
+					c = prev + (next-prev)*float64(k-i+1)/float64(j-i+1)
+				}
+				out = append(out, PricePoint{Date: days[k], Close: c})
+				if cal != nil && !cal.IsOpen(days[k]) {
+					stats.Closed++
+				} else {
+					stats.Missing++
+				}
+			}
+		}
+		i = j
+	}
+	return Series{Symbol: s.Symbol, Points: out}, stats
+}
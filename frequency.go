@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Frequency describes the period the analysis runs on: how bars are bucketed, how the
+// buckets are labelled in outputs and how per-period statistics are annualized.
+type Frequency struct {
+	Name           string
+	Title          string
+	Unit           string // short period name for compact labels
+	PeriodsPerYear float64
+	Key            func(time.Time) time.Time
+	Label          func(time.Time) string
+}
+
+var monthlyFrequency = Frequency{
+	Name:           "monthly",
+	Title:          "Monthly",
+	Unit:           "mo",
+	PeriodsPerYear: 12,
+	Key: func(t time.Time) time.Time {
+		y, m, _ := t.Date()
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	},
+	Label: func(t time.Time) string { return t.Format("2006-01") },
+}
+
+// weeklyFrequency buckets by ISO week, keyed by the Monday the week starts on.
+var weeklyFrequency = Frequency{
+	Name:           "weekly",
+	Title:          "Weekly",
+	Unit:           "wk",
+	PeriodsPerYear: 52,
+	Key: func(t time.Time) time.Time {
+		y, m, d := t.Date()
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	},
+	Label: func(t time.Time) string {
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", y, w)
+	},
+}
+
+// dailyFrequency compares individual trading days; it requires daily bars.
+var dailyFrequency = Frequency{
+	Name:           "daily",
+	Title:          "Daily",
+	Unit:           "day",
+	PeriodsPerYear: 252,
+	Key: func(t time.Time) time.Time {
+		y, m, d := t.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	},
+	Label: func(t time.Time) string { return t.Format("2006-01-02") },
+}
+
+// frequencyForInterval runs weekly bars natively on ISO weeks and resamples daily and
+// monthly bars to months.
+func frequencyForInterval(interval string) Frequency {
+	if interval == "1wk" {
+		return weeklyFrequency
+	}
+	return monthlyFrequency
+}
+
+// selectFrequency resolves the -frequency flag; auto follows the interval.
+func selectFrequency(name string, interval string) (Frequency, error) {
+	switch name {
+	case "", "auto":
+		return frequencyForInterval(interval), nil
+	case "monthly":
+		return monthlyFrequency, nil
+	case "weekly":
+		if interval != "1d" && interval != "1wk" {
+			return Frequency{}, fmt.Errorf("weekly frequency needs -interval 1d or 1wk")
+		}
+		return weeklyFrequency, nil
+	case "daily":
+		if interval != "1d" {
+			return Frequency{}, fmt.Errorf("daily frequency needs -interval 1d")
+		}
+		return dailyFrequency, nil
+	}
+	return Frequency{}, fmt.Errorf("unknown frequency %q (want auto, monthly, weekly or daily)", name)
+}
+
+// periodSeries returns the last close of each period keyed by the period start.
+func periodSeries(points []PricePoint, freq Frequency) map[time.Time]float64 {
+	m := make(map[time.Time]float64)
+	for _, p := range points {
+		m[freq.Key(p.Date)] = p.Close
+	}
+	return m
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseGlidePath parses -glide-path: comma separated ETF weights placed at evenly spaced
+// points of the period, e.g. 0.6,0.6,0.9 holds 60% for the first half and then rises to 90%.
+func parseGlidePath(value string) ([]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("glide-path needs at least two weights")
+	}
+	out := make([]float64, len(parts))
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glide-path weight %q", part)
+		}
+		if err := validateWeight("glide-path weights", v); err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// pathWeights interpolates linearly between points spread evenly over count periods, so the
+// first period holds the first point and the last period the last one.
+func pathWeights(count int, points []float64) []float64 {
+	if count <= 1 {
+		return []float64{points[len(points)-1]}
+	}
+	weights := make([]float64, count)
+	segments := float64(len(points) - 1)
+	for i := range weights {
+		pos := float64(i) / float64(count-1) * segments
+		k := int(pos)
+		if k >= len(points)-1 {
+			weights[i] = points[len(points)-1]
+			continue
+		}
+		weights[i] = points[k] + (pos-float64(k))*(points[k+1]-points[k])
+	}
+	return weights
+}
+
+// weightedReturns blends the aligned returns with a per-period ETF weight.
+func weightedReturns(retsA []float64, retsB []float64, weights []float64) []float64 {
+	out := make([]float64, len(retsA))
+	for i := range retsA {
+		out[i] = retsA[i]*weights[i] + retsB[i]*(1-weights[i])
+	}
+	return out
+}
+
+// glideDirection describes the shape of a glide path segment by segment, merging repeats:
+// "descending", "ascending", "flat" or e.g. "flat then ascending".
+func glideDirection(points []float64) string {
+	steps := make([]string, 0, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		step := "flat"
+		if points[i] > points[i-1] {
+			step = "ascending"
+		} else if points[i] < points[i-1] {
+			step = "descending"
+		}
+		if len(steps) == 0 || steps[len(steps)-1] != step {
+			steps = append(steps, step)
+		}
+	}
+	return strings.Join(steps, " then ")
+}
+
+// formatGlide renders glide points as percentages joined by arrows.
+func formatGlide(points []float64) string {
+	parts := make([]string, len(points))
+	for i, v := range points {
+		parts[i] = fmt.Sprintf("%.0f%%", v*100)
+	}
+	return strings.Join(parts, " → ")
+}
+
+// AgeGlide derives the glide path from the investor's age: the ETF weight is Base minus the
+// age in percent, with the age held at RetireAge once retired. A zero BirthYear disables it.
+type AgeGlide struct {
+	BirthYear int
+	RetireAge int
+	Base      float64
+}
+
+// ageWeights returns the ETF weight of every period under the age rule, limited to 0–1.
+func ageWeights(dates []time.Time, rule AgeGlide) []float64 {
+	weights := make([]float64, len(dates))
+	for i, d := range dates {
+		daysInYear := time.Date(d.Year(), time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+		age := float64(d.Year()-rule.BirthYear) + float64(d.YearDay()-1)/float64(daysInYear)
+		age = math.Min(age, float64(rule.RetireAge))
+		weights[i] = math.Max(0, math.Min(1, (rule.Base-age)/100))
+	}
+	return weights
+}
+
+// validateAgeGlide checks the -birth-year, -retire-age and -age-rule values.
+func validateAgeGlide(rule AgeGlide, now time.Time) error {
+	if rule.BirthYear < 1900 || rule.BirthYear > now.Year() {
+		return fmt.Errorf("birth-year must be between 1900 and %d", now.Year())
+	}
+	if rule.RetireAge <= 0 || rule.RetireAge > 120 {
+		return fmt.Errorf("retire-age must be between 1 and 120")
+	}
+	if rule.Base <= 0 {
+		return fmt.Errorf("age-rule must be positive")
+	}
+	return nil
+}
+
+// BondTent de-risks linearly into a retirement date and re-risks after it: the ETF weight
+// falls by Depth from the first glide weight over Years before Retire and recovers over Years
+// after. A zero Retire disables it.
+type BondTent struct {
+	Retire time.Time
+	Depth  float64
+	Years  float64
+}
+
+// tentWeights returns the ETF weight of every period under the bond tent around base.
+func tentWeights(dates []time.Time, base float64, tent BondTent) []float64 {
+	weights := make([]float64, len(dates))
+	for i, d := range dates {
+		years := math.Abs(d.Sub(tent.Retire).Hours()) / (24 * 365.25)
+		weights[i] = base - tent.Depth*math.Max(0, 1-years/tent.Years)
+	}
+	return weights
+}
+
+// validateBondTent checks -tent-depth and -tent-years against the weight the tent starts from.
+func validateBondTent(tent BondTent, base float64) error {
+	if tent.Depth < 0 || tent.Depth > base {
+		return fmt.Errorf("tent-depth must be between 0 and the starting glide weight %.2f", base)
+	}
+	if tent.Years <= 0 {
+		return fmt.Errorf("tent-years must be positive")
+	}
+	return nil
+}
+
+// loadWeightOverrides reads a month,weight CSV of ETF weights that replace the glide path's
+// weight in every period of that month. Months may be YYYY-MM or YYYY-MM-DD and a header row
+// is skipped.
+func loadWeightOverrides(path string) (map[time.Time]float64, error) {
+	f, err := outputFS.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open weights: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return parseWeightOverrides(f)
+}
+
+func parseWeightOverrides(r io.Reader) (map[time.Time]float64, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	out := make(map[time.Time]float64)
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read weights: %w", err)
+		}
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("weights line %d: want month,weight", line)
+		}
+		d, err := time.Parse("2006-01-02", rec[0])
+		if err != nil {
+			d, err = time.Parse("2006-01", rec[0])
+		}
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("weights line %d: invalid date %q", line, rec[0])
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(rec[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("weights line %d: invalid weight %q", line, rec[1])
+		}
+		if err := validateWeight(fmt.Sprintf("weights line %d", line), v); err != nil {
+			return nil, err
+		}
+		out[monthlyFrequency.Key(d)] = v
+	}
+	return out, nil
+}
+
+// applyOverrides replaces the weight of every period whose month has an override and returns
+// how many periods changed.
+func applyOverrides(dates []time.Time, weights []float64, overrides map[time.Time]float64) int {
+	n := 0
+	for i, d := range dates {
+		if v, ok := overrides[monthlyFrequency.Key(d)]; ok {
+			weights[i] = v
+			n++
+		}
+	}
+	return n
+}
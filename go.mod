@@ -3,3 +3,5 @@ module yahoo_finance_ae
 go 1.22.5
 
 require github.com/oscarli916/yahoo-finance-api v0.1.2
+
+require github.com/klauspost/compress v1.17.11
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// upcomingPeriods returns the first weekday of each of the next n periods after from.
+func upcomingPeriods(from time.Time, n int, freq Frequency) []time.Time {
+	out := make([]time.Time, 0, n)
+	y, m, d := from.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	key := freq.Key(day)
+	for len(out) < n {
+		day = day.AddDate(0, 0, 1)
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		if k := freq.Key(day); !k.Equal(key) {
+			key = k
+			out = append(out, day)
+		}
+	}
+	return out
+}
+
+// rebalancePlan returns the GlidePath ETF weight of each upcoming date. Age-based paths and
+// weight overrides follow the calendar; other glide paths hold their end weight after the
+// analysed period.
+func rebalancePlan(dates []time.Time, p Params) []float64 {
+	base := make([]float64, len(dates))
+	for i := range base {
+		base[i] = p.Glide[len(p.Glide)-1]
+	}
+	weights, _ := calendarWeights(dates, p, base)
+	return weights
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// rebalanceCalendar renders the upcoming rebalance dates as an iCalendar file with the target
+// weights of both strategies in each event's description.
+func rebalanceCalendar(dates []time.Time, glide []float64, p Params, now time.Time) string {
+	w := &bytes.Buffer{}
+	line := func(format string, args ...any) {
+		_, _ = fmt.Fprintf(w, format, args...)
+		_, _ = w.WriteString("\r\n")
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//yahoo_finance_ae//rebalance plan//EN")
+	for i, d := range dates {
+		desc := fmt.Sprintf("LifeStrategy: %.0f%% %s, %.0f%% %s\nGlidePath: %.0f%% %s, %.0f%% %s",
+			p.LifeWeight*100, p.ETF, (1-p.LifeWeight)*100, p.Index, glide[i]*100, p.ETF, (1-glide[i])*100, p.Index)
+		line("BEGIN:VEVENT")
+		line("UID:%s-%s-%s@yahoo_finance_ae", d.Format("20060102"), icsEscaper.Replace(p.ETF), icsEscaper.Replace(p.Index))
+		line("DTSTAMP:%s", now.UTC().Format("20060102T150405Z"))
+		line("DTSTART;VALUE=DATE:%s", d.Format("20060102"))
+		line("SUMMARY:%s", icsEscaper.Replace(fmt.Sprintf("Rebalance %s / %s", p.ETF, p.Index)))
+		line("DESCRIPTION:%s", icsEscaper.Replace(desc))
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return w.String()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+type YearRow struct {
+	Year   int
+	Months int
+	ETF    float64
+	Index  float64
+	Diff   float64
+}
+
+// yearlyReturns compounds aligned periodic returns into calendar-year returns.
+func yearlyReturns(dates []time.Time, retsA []float64, retsB []float64) []YearRow {
+	out := make([]YearRow, 0)
+	for i, d := range dates {
+		if math.IsNaN(retsA[i]) || math.IsNaN(retsB[i]) {
+			continue
+		}
+		y := d.Year()
+		if len(out) == 0 || out[len(out)-1].Year != y {
+			out = append(out, YearRow{Year: y, ETF: 1, Index: 1})
+		}
+		row := &out[len(out)-1]
+		row.Months++
+		row.ETF *= 1 + retsA[i]
+		row.Index *= 1 + retsB[i]
+	}
+	for i := range out {
+		out[i].ETF--
+		out[i].Index--
+		out[i].Diff = out[i].ETF - out[i].Index
+	}
+	return out
+}
+
+// trackingError returns the annualized sample standard deviation of periodic alpha.
+func trackingError(alphas []float64, periodsPerYear float64) float64 {
+	if len(alphas) < 2 {
+		return 0
+	}
+	mean := 0.0
+	for _, a := range alphas {
+		mean += a
+	}
+	mean /= float64(len(alphas))
+	ss := 0.0
+	for _, a := range alphas {
+		ss += (a - mean) * (a - mean)
+	}
+	return math.Sqrt(ss/float64(len(alphas)-1)) * math.Sqrt(periodsPerYear)
+}
+
+var latexReplacer = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`^`, `\textasciicircum{}`,
+	`~`, `\textasciitilde{}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`{`, `\{`,
+	`}`, `\}`,
+)
+
+func writeLaTeXTables(path string, res Result) error {
+	var buf bytes.Buffer
+	if err := renderLaTeXTables(&buf, res); err != nil {
+		return err
+	}
+	if _, err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("write latex: %w", err)
+	}
+	return nil
+}
+
+// renderLaTeXTables writes the yearly and summary tabulars to out.
+func renderLaTeXTables(out io.Writer, res Result) error {
+	w := bufio.NewWriter(out)
+
+	etf := latexReplacer.Replace(res.Params.ETF)
+	idx := latexReplacer.Replace(res.Params.Index)
+
+	_, _ = w.WriteString("% Yearly returns\n")
+	_, _ = w.WriteString("\\begin{tabular}{lrrrr}\n\\hline\n")
+	_, _ = fmt.Fprintf(w, "Year & Periods & %s (\\%%) & %s (\\%%) & Difference (\\%%) \\\\\n\\hline\n", etf, idx)
+	for _, y := range res.Years {
+		_, _ = fmt.Fprintf(w, "%d & %d & %.2f & %.2f & %.2f \\\\\n", y.Year, y.Months, y.ETF*100, y.Index*100, y.Diff*100)
+	}
+	_, _ = w.WriteString("\\hline\n\\end{tabular}\n\n")
+
+	last := res.Rows[len(res.Rows)-1]
+	_, _ = w.WriteString("% Summary\n")
+	_, _ = w.WriteString("\\begin{tabular}{lr}\n\\hline\n")
+	_, _ = w.WriteString("Metric & Value \\\\\n\\hline\n")
+	_, _ = fmt.Fprintf(w, "Period & %s--%s \\\\\n", res.Rows[0].Date, last.Date)
+	_, _ = fmt.Fprintf(w, "Periods ETF $>$ index & %d/%d \\\\\n", res.WinCount, res.Total)
+	_, _ = fmt.Fprintf(w, "Average alpha per period & %.5f \\\\\n", res.AvgAlpha)
+	_, _ = fmt.Fprintf(w, "Tracking error (annualized) & %.5f \\\\\n", res.TrackingError)
+	_, _ = fmt.Fprintf(w, "Final %s (base 100) & %.2f \\\\\n", etf, last.ETF)
+	_, _ = fmt.Fprintf(w, "Final %s (base 100) & %.2f \\\\\n", idx, last.Index)
+	for _, m := range res.Metrics {
+		_, _ = fmt.Fprintf(w, "%s & %s \\\\\n", latexReplacer.Replace(m.Title), latexReplacer.Replace(m.Text))
+	}
+	_, _ = w.WriteString("\\hline\n\\end{tabular}\n")
+	return w.Flush()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"time"
+)
+
+// LifecyclePhase summarizes one phase of a lifecycle simulation for one strategy.
+type LifecyclePhase struct {
+	Periods  int
+	Flow     float64 // total contributed or withdrawn
+	Start    float64
+	End      float64
+	MaxDD    float64
+	Depleted int // period index at which the money ran out, or -1
+}
+
+// lifecyclePath grows an initial 100 by the given returns, contributing at the start of every
+// period before switchAt and withdrawing from it afterwards, and summarizes both phases.
+func lifecyclePath(returns []float64, switchAt int, contribution float64, withdrawal float64) (accumulation LifecyclePhase, decumulation LifecyclePhase) {
+	v := 100.0
+	accumulation = LifecyclePhase{Periods: switchAt, Start: v, Depleted: -1}
+	for _, r := range returns[:switchAt] {
+		v = (v + contribution) * (1 + r)
+		accumulation.Flow += contribution
+	}
+	accumulation.End = v
+	accumulation.MaxDD = maxDrawdown(returns[:switchAt])
+
+	decumulation = LifecyclePhase{Periods: len(returns) - switchAt, Start: v, Depleted: -1}
+	for i, r := range returns[switchAt:] {
+		take := withdrawal
+		if take >= v {
+			take = v
+			decumulation.Depleted = switchAt + i
+		}
+		decumulation.Flow += take
+		v = (v - take) * (1 + r)
+		if decumulation.Depleted >= 0 {
+			break
+		}
+	}
+	decumulation.End = v
+	decumulation.MaxDD = maxDrawdown(returns[switchAt:])
+	return accumulation, decumulation
+}
+
+// LifecycleReport is a lifecycle simulation of every strategy, in chartSeries order,
+// switching from contributing to withdrawing at period SwitchAt.
+type LifecycleReport struct {
+	Dates        []time.Time
+	SwitchDate   time.Time
+	SwitchAt     int
+	Contribution float64
+	Withdrawal   float64
+	Accumulation []LifecyclePhase
+	Decumulation []LifecyclePhase
+}
+
+// lifecycleReport simulates each strategy, switching at the first period on or after
+// switchDate.
+func lifecycleReport(dates []time.Time, strategies [][]float64, switchDate time.Time, contribution float64, withdrawal float64) LifecycleReport {
+	r := LifecycleReport{
+		Dates:        dates,
+		SwitchDate:   switchDate,
+		SwitchAt:     sort.Search(len(dates), func(i int) bool { return !dates[i].Before(switchDate) }),
+		Contribution: contribution,
+		Withdrawal:   withdrawal,
+	}
+	for j := range chartSeries {
+		acc, dec := lifecyclePath(strategies[j], r.SwitchAt, contribution, withdrawal)
+		r.Accumulation = append(r.Accumulation, acc)
+		r.Decumulation = append(r.Decumulation, dec)
+	}
+	return r
+}
+
+// summary describes the two phases in one line.
+func (r LifecycleReport) summary(freq Frequency) string {
+	return fmt.Sprintf("%d %s periods contributing %.2f, then %d withdrawing %.2f from %s",
+		r.SwitchAt, freq.Name, r.Contribution, len(r.Dates)-r.SwitchAt, r.Withdrawal, r.SwitchDate.Format("2006-01-02"))
+}
+
+// depleted labels the period a strategy's money ran out, or "no".
+func (r LifecycleReport) depleted(dec LifecyclePhase, freq Frequency) string {
+	if dec.Depleted < 0 {
+		return "no"
+	}
+	return freq.Label(r.Dates[dec.Depleted])
+}
+
+// writeLifecycleReport prints the accumulation and decumulation phases of each strategy.
+func writeLifecycleReport(w io.Writer, r LifecycleReport, freq Frequency) {
+	fmt.Fprintf(w, "Lifecycle: %s\n", r.summary(freq))
+	fmt.Fprintf(w, "%-14s %12s %12s %9s | %12s %12s %9s %s\n", "Strategy", "Contributed", "At switch", "Max DD", "Withdrawn", "Final", "Max DD", "Depleted")
+	for j, cs := range chartSeries {
+		acc, dec := r.Accumulation[j], r.Decumulation[j]
+		fmt.Fprintf(w, "%-14s %12.2f %12.2f %8.2f%% | %12.2f %12.2f %8.2f%% %s\n",
+			cs.Name, acc.Flow, acc.End, acc.MaxDD*100, dec.Flow, dec.End, dec.MaxDD*100, r.depleted(dec, freq))
+	}
+}
+
+// lifecycleHTML renders the lifecycle comparison as a report section.
+func lifecycleHTML(r LifecycleReport, freq Frequency) string {
+	w := &bytes.Buffer{}
+	_, _ = w.WriteString("<h2>Lifecycle</h2>\n")
+	_, _ = fmt.Fprintf(w, "<div class=\"meta\">%s</div>\n", html.EscapeString(r.summary(freq)))
+	_, _ = w.WriteString("<table>\n<thead><tr><th>Strategy</th><th>Contributed</th><th>At switch</th><th>Max DD</th><th>Withdrawn</th><th>Final</th><th>Max DD</th><th>Depleted</th></tr></thead>\n<tbody>\n")
+	for j, cs := range chartSeries {
+		acc, dec := r.Accumulation[j], r.Decumulation[j]
+		_, _ = fmt.Fprintf(w, "<tr><td>%s</td><td>%.2f</td><td>%.2f</td><td>%.2f%%</td><td>%.2f</td><td>%.2f</td><td>%.2f%%</td><td>%s</td></tr>\n",
+			cs.Name, acc.Flow, acc.End, acc.MaxDD*100, dec.Flow, dec.End, dec.MaxDD*100, r.depleted(dec, freq))
+	}
+	_, _ = w.WriteString("</tbody>\n</table>\n")
+	return w.String()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+var errSingular = errors.New("singular system")
+
+// solveLinear solves the k×k system held in the first k columns of the augmented matrix m
+// for every right-hand side column after them, in place, by Gauss-Jordan elimination with
+// partial pivoting: the solutions replace the right-hand sides, and an identity appended
+// as right-hand sides becomes the inverse. It returns errSingular when no pivot reaches tol.
+func solveLinear(m [][]float64, tol float64) error {
+	k := len(m)
+	for p := 0; p < k; p++ {
+		best := p
+		for i := p + 1; i < k; i++ {
+			if math.Abs(m[i][p]) > math.Abs(m[best][p]) {
+				best = i
+			}
+		}
+		if math.Abs(m[best][p]) < tol {
+			return errSingular
+		}
+		m[p], m[best] = m[best], m[p]
+		pivot := m[p][p]
+		for j := range m[p] {
+			m[p][j] /= pivot
+		}
+		for i := 0; i < k; i++ {
+			if i == p {
+				continue
+			}
+			factor := m[i][p]
+			for j := range m[i] {
+				m[i][j] -= factor * m[p][j]
+			}
+		}
+	}
+	return nil
+}
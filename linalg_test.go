@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSolveLinear(t *testing.T) {
+	// y = 2 and x + 3y = 7, with the identity appended for the inverse. The zero leading
+	// coefficient needs a row swap.
+	sys := [][]float64{
+		{0, 1, 2, 1, 0},
+		{1, 3, 7, 0, 1},
+	}
+	if err := solveLinear(sys, 1e-12); err != nil {
+		t.Fatal(err)
+	}
+	// x = 1, y = 2; the inverse of [[0 1] [1 3]] is [[-3 1] [1 0]].
+	want := [][]float64{{1, 0, 1, -3, 1}, {0, 1, 2, 1, 0}}
+	for i := range want {
+		for j := range want[i] {
+			if math.Abs(sys[i][j]-want[i][j]) > 1e-12 {
+				t.Fatalf("row %d = %v, want %v", i, sys[i], want[i])
+			}
+		}
+	}
+
+	singular := [][]float64{{1, 2, 3}, {2, 4, 6}}
+	if err := solveLinear(singular, 1e-12); !errors.Is(err, errSingular) {
+		t.Errorf("singular system: err %v, want errSingular", err)
+	}
+}
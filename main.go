@@ -2,18 +2,25 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/base64"
 	"flag"
 	"fmt"
+	"html"
+	"io"
 	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
-
-	yahoofinanceapi "github.com/oscarli916/yahoo-finance-api"
 )
 
+// chartJSCDN pins the Chart.js build used by reports so old reports keep rendering
+// when a new major version changes the API.
+const chartJSCDN = "https://cdn.jsdelivr.net/npm/chart.js@4.4.1/dist/chart.umd.min.js"
+
 type PricePoint struct {
 	Date  time.Time
 	Close float64
@@ -25,65 +32,25 @@ type Series struct {
 }
 
 type ReportRow struct {
-	Date   string
-	ETF    float64
-	Index  float64
-	Alpha  float64
-	Life   float64
-	Glide  float64
-	Weight float64
-}
-
-func loadFromYahoo(symbol string, query yahoofinanceapi.HistoryQuery) (Series, error) {
-	ticker := yahoofinanceapi.NewTicker(symbol)
-	data, err := ticker.History(query)
-	if err != nil {
-		return Series{}, fmt.Errorf("history error %s: %w", symbol, err)
-	}
-
-	points := make([]PricePoint, 0, len(data))
-	skipped := 0
-	for dateStr, price := range data {
-		if math.IsNaN(price.Close) || price.Close <= 0 {
-			skipped++
-			continue
-		}
-
-		d, err := time.Parse("2006-01-02", dateStr)
-		if err != nil {
-			d, err = time.Parse("2006-01-02 15:04:05", dateStr)
-			if err != nil {
-				return Series{}, fmt.Errorf("parse date %s for %s: %w", dateStr, symbol, err)
-			}
-		}
-
-		points = append(points, PricePoint{
-			Date:  d.UTC(),
-			Close: price.Close,
-		})
-	}
-
-	sort.Slice(points, func(i, j int) bool { return points[i].Date.Before(points[j].Date) })
-	if skipped > 0 {
-		fmt.Fprintf(os.Stderr, "Ticker %s: skipped %d NaN Close points\n", symbol, skipped)
-	}
-
-	return Series{Symbol: symbol, Points: points}, nil
-}
-
-// monthlySeries returns the last close of each month keyed by the first day of month (UTC).
-func monthlySeries(points []PricePoint) map[time.Time]float64 {
-	m := make(map[time.Time]float64)
-	for _, p := range points {
-		y, mon, _ := p.Date.Date()
-		key := time.Date(y, mon, 1, 0, 0, 0, 0, time.UTC)
-		m[key] = p.Close
-	}
-	return m
+	Period      time.Time
+	Date        string
+	ETF         float64
+	Index       float64
+	Alpha       float64
+	Life        float64
+	Glide       float64
+	Weight      float64
+	ETFReturn   float64
+	IndexReturn float64
+	LifeReturn  float64
+	GlideReturn float64
+	GlideTraded float64
 }
 
-// monthlyReturns converts monthly prices into month-over-month returns.
-func monthlyReturns(series map[time.Time]float64) ([]time.Time, []float64) {
+// monthlyReturns converts period closes (monthly, or weekly for 1wk bars) into
+// period-over-period returns. When divs is not nil, distributions with an ex-date in a
+// period are added back to that period's return.
+func monthlyReturns(series map[time.Time]float64, divs map[time.Time]float64) ([]time.Time, []float64) {
 	if len(series) == 0 {
 		return nil, nil
 	}
@@ -104,7 +71,7 @@ func monthlyReturns(series map[time.Time]float64) ([]time.Time, []float64) {
 		if p0 == 0 {
 			continue
 		}
-		rets = append(rets, p1/p0-1.0)
+		rets = append(rets, (p1+divs[d1])/p0-1.0)
 		outDates = append(outDates, d1)
 	}
 	return outDates, rets
@@ -148,18 +115,6 @@ func blendReturns(retsA []float64, retsB []float64, weightA float64) []float64 {
 	return out
 }
 
-func glideWeights(count int, start float64, end float64) []float64 {
-	if count <= 1 {
-		return []float64{end}
-	}
-	weights := make([]float64, count)
-	step := (end - start) / float64(count-1)
-	for i := 0; i < count; i++ {
-		weights[i] = start + float64(i)*step
-	}
-	return weights
-}
-
 func validateWeight(name string, v float64) error {
 	if v < 0 || v > 1 {
 		return fmt.Errorf("%s must be between 0 and 1", name)
@@ -172,28 +127,29 @@ func parseDate(value string) error {
 	return err
 }
 
-func writeHTMLReport(path string, etfSymbol string, idxSymbol string, startDate string, interval string, lifeWeight float64, glideStart float64, glideEnd float64, rows []ReportRow, avgAlpha float64, winCount int, total int) (string, error) {
-	absPath, err := filepath.Abs(path)
-	if err != nil {
-		return "", fmt.Errorf("resolve html path: %w", err)
-	}
-	f, err := os.Create(absPath)
-	if err != nil {
-		return "", fmt.Errorf("create html: %w", err)
+// csvFileName builds a download name from the symbols, dropping characters such as ^ that
+// browsers reject in file names.
+func csvFileName(etfSymbol string, idxSymbol string) string {
+	clean := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+				return r
+			}
+			return -1
+		}, s)
 	}
-	defer func() {
-		_ = f.Close()
-	}()
+	return clean(etfSymbol) + "_vs_" + clean(idxSymbol) + ".csv"
+}
 
-	w := bufio.NewWriter(f)
-	defer func() {
-		_ = w.Flush()
-	}()
+// writeHTMLReport writes the HTML report page to out.
+func writeHTMLReport(out io.Writer, res Result, csvData []byte, chartJSSrc string, charts ChartOptions) error {
+	p := res.Params
+	w := bufio.NewWriter(out)
 
 	_, _ = w.WriteString("<!doctype html>\n<html lang=\"it\">\n<head>\n<meta charset=\"utf-8\">\n")
 	_, _ = w.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
 	_, _ = w.WriteString("<title>ETF vs Index Report</title>\n")
-	_, _ = w.WriteString("<script src=\"https://cdn.jsdelivr.net/npm/chart.js\"></script>\n")
+	_, _ = fmt.Fprintf(w, "<script src=\"%s\"></script>\n", html.EscapeString(chartJSSrc))
 	_, _ = w.WriteString("<style>\n")
 	_, _ = w.WriteString("body{font-family:Arial,Helvetica,sans-serif;background:#f6f7fb;color:#1b1b1b;margin:0;padding:24px}\n")
 	_, _ = w.WriteString(".wrap{max-width:1200px;margin:0 auto}\n")
@@ -208,31 +164,123 @@ func writeHTMLReport(path string, etfSymbol string, idxSymbol string, startDate
 	_, _ = w.WriteString("th,td{padding:8px 10px;border-bottom:1px solid #eef0f5;text-align:right;font-size:13px}\n")
 	_, _ = w.WriteString("th:first-child,td:first-child{text-align:left}\n")
 	_, _ = w.WriteString("thead{background:#f0f3fb}\n")
+	_, _ = w.WriteString(".alert{background:#fdecea;border:1px solid #f5c2c0;color:#8a1c13;border-radius:10px;padding:12px 16px;margin-bottom:16px}\n")
+	_, _ = w.WriteString(".download{display:inline-block;margin:20px 0 0 0;padding:8px 14px;background:#1f77b4;color:#fff;border-radius:6px;text-decoration:none;font-size:13px}\n")
 	_, _ = w.WriteString("</style>\n</head>\n<body>\n<div class=\"wrap\">\n")
 	_, _ = fmt.Fprintf(w, "<h1>ETF vs Index</h1>\n")
-	_, _ = fmt.Fprintf(w, "<div class=\"meta\">ETF: %s | Index: %s | Start: %s | Interval: %s</div>\n", etfSymbol, idxSymbol, startDate, interval)
+	window := "Start: " + p.StartDate
+	if p.EndDate != "" {
+		window += " | End: " + p.EndDate
+	}
+	_, _ = fmt.Fprintf(w, "<div class=\"meta\">ETF: %s | Index: %s | %s | Interval: %s</div>\n", p.ETF, p.Index, window, p.Interval)
+	if len(charts.Alerts) > 0 {
+		_, _ = w.WriteString("<div class=\"alert\"><b>ALERT</b>\n<ul>\n")
+		for _, a := range charts.Alerts {
+			_, _ = fmt.Fprintf(w, "<li>%s</li>\n", html.EscapeString(a))
+		}
+		_, _ = w.WriteString("</ul>\n</div>\n")
+	}
 	_, _ = w.WriteString("<div class=\"cards\">\n")
-	_, _ = fmt.Fprintf(w, "<div class=\"card\"><div class=\"label\">Win rate</div><div class=\"value\">%d/%d</div></div>\n", winCount, total)
-	_, _ = fmt.Fprintf(w, "<div class=\"card\"><div class=\"label\">Avg alpha</div><div class=\"value\">%.5f</div></div>\n", avgAlpha)
-	_, _ = fmt.Fprintf(w, "<div class=\"card\"><div class=\"label\">Life ETF weight</div><div class=\"value\">%.2f</div></div>\n", lifeWeight)
-	_, _ = fmt.Fprintf(w, "<div class=\"card\"><div class=\"label\">Glide start/end</div><div class=\"value\">%.2f → %.2f</div></div>\n", glideStart, glideEnd)
+	_, _ = fmt.Fprintf(w, "<div class=\"card\"><div class=\"label\">Win rate</div><div class=\"value\">%d/%d</div></div>\n", res.WinCount, res.Total)
+	_, _ = fmt.Fprintf(w, "<div class=\"card\"><div class=\"label\">Avg alpha</div><div class=\"value\">%.5f</div></div>\n", res.AvgAlpha)
+	_, _ = fmt.Fprintf(w, "<div class=\"card\"><div class=\"label\">Life ETF weight</div><div class=\"value\">%.2f</div></div>\n", p.LifeWeight)
+	switch {
+	case p.Age.BirthYear != 0:
+		_, _ = fmt.Fprintf(w, "<div class=\"card\"><div class=\"label\">Glide path (%s)</div><div class=\"value\">%.0f − age, born %d</div></div>\n", glideDirection(res.GlideWeights), p.Age.Base, p.Age.BirthYear)
+	default:
+		_, _ = fmt.Fprintf(w, "<div class=\"card\"><div class=\"label\">Glide path (%s)</div><div class=\"value\">%s</div></div>\n", glideDirection(p.Glide), formatGlide(p.Glide))
+	}
+	if res.TentWeights != nil {
+		_, _ = fmt.Fprintf(w, "<div class=\"card\"><div class=\"label\">Bond tent (%s)</div><div class=\"value\">%.0f%% − %.0f%% at %s</div></div>\n", glideDirection(res.TentWeights), p.Glide[0]*100, p.Tent.Depth*100, p.Tent.Retire.Format("2006-01-02"))
+	}
+	for _, m := range res.Metrics {
+		_, _ = fmt.Fprintf(w, "<div class=\"card\"><div class=\"label\">%s</div><div class=\"value\">%s</div></div>\n", html.EscapeString(m.Title), m.Text)
+	}
 	_, _ = w.WriteString("</div>\n")
-	_, _ = w.WriteString("<canvas id=\"cumChart\" height=\"120\"></canvas>\n")
+	_, _ = fmt.Fprintf(w, "<canvas id=\"cumChart\" height=\"%d\"></canvas>\n", charts.CumHeight)
+	_, _ = w.WriteString(whatIfHTML(p.LifeWeight))
+	// The editor starts from at least five points sampled from the report's glide weights.
+	editorPoints := min(max(len(p.Glide), 5), len(res.Rows))
+	if editorPoints >= 2 {
+		_, _ = w.WriteString(glideEditorHTML(charts.AlphaHeight))
+	}
 	_, _ = w.WriteString("<div style=\"height:16px\"></div>\n")
-	_, _ = w.WriteString("<canvas id=\"alphaChart\" height=\"90\"></canvas>\n")
+	_, _ = fmt.Fprintf(w, "<canvas id=\"alphaChart\" height=\"%d\"></canvas>\n", charts.AlphaHeight)
+	if len(charts.Regimes) > 0 {
+		_, _ = w.WriteString(regimeTable(charts.Regimes))
+	}
+	if charts.Pair != nil {
+		_, _ = w.WriteString(pairHTML(p.ETF, *charts.Pair, charts.AlphaHeight))
+	}
+	if len(charts.YieldLines) > 0 {
+		_, _ = w.WriteString("<h2>Dividend yield (trailing 12 months)</h2>\n")
+		_, _ = fmt.Fprintf(w, "<canvas id=\"yieldChart\" height=\"%d\"></canvas>\n", charts.AlphaHeight)
+	}
+	if len(charts.RealLines) > 0 {
+		_, _ = w.WriteString("<h2>Nominal vs real</h2>\n")
+		_, _ = fmt.Fprintf(w, "<canvas id=\"realChart\" height=\"%d\"></canvas>\n", charts.CumHeight)
+		_, _ = w.WriteString("<table>\n<thead><tr><th>Strategy</th><th>Nominal (annualized)</th><th>Real (annualized)</th></tr></thead>\n<tbody>\n")
+		for _, r := range charts.RealTable {
+			_, _ = fmt.Fprintf(w, "<tr><td>%s</td><td>%.2f%%</td><td>%.2f%%</td></tr>\n", r.Name, r.Nominal*100, r.Real*100)
+		}
+		_, _ = w.WriteString("</tbody>\n</table>\n")
+	}
+	if len(charts.Savings) > 0 {
+		_, _ = w.WriteString(savingsHeatmap(charts.Savings, charts.SavingsGoal))
+	}
+	if charts.Lifecycle != nil {
+		_, _ = w.WriteString(lifecycleHTML(*charts.Lifecycle, p.Freq))
+	}
+	if len(charts.StyleLines) > 0 {
+		_, _ = w.WriteString("<h2>Inferred benchmark mix (rolling style analysis)</h2>\n")
+		_, _ = fmt.Fprintf(w, "<canvas id=\"styleChart\" height=\"%d\"></canvas>\n", charts.AlphaHeight)
+	}
+	if len(charts.Sweep) > 0 {
+		_, _ = w.WriteString("<h2>ETF weight sweep</h2>\n")
+		_, _ = fmt.Fprintf(w, "<canvas id=\"sweepChart\" height=\"%d\"></canvas>\n", charts.AlphaHeight)
+	}
+	if len(charts.Overlay) > 0 {
+		_, _ = fmt.Fprintf(w, "<h2>%s across windows</h2>\n", charts.OverlayName)
+		_, _ = fmt.Fprintf(w, "<canvas id=\"overlayChart\" height=\"%d\"></canvas>\n", charts.AlphaHeight)
+	}
+	if len(charts.Frontier) > 0 {
+		_, _ = w.WriteString("<h2>Efficient frontier</h2>\n")
+		_, _ = fmt.Fprintf(w, "<canvas id=\"frontierChart\" height=\"%d\"></canvas>\n", charts.AlphaHeight)
+	}
+
+	if len(res.Strategies) > 0 && len(res.Strategies[0].Results) > 0 {
+		_, _ = w.WriteString("<h2>Strategy metrics</h2>\n<table>\n<thead><tr><th>Metric</th>")
+		for _, st := range res.Strategies {
+			_, _ = fmt.Fprintf(w, "<th>%s</th>", st.Name)
+		}
+		_, _ = w.WriteString("</tr></thead>\n<tbody>\n")
+		for mi, m := range res.Strategies[0].Results {
+			_, _ = fmt.Fprintf(w, "<tr><td>%s</td>", html.EscapeString(m.Title))
+			for _, st := range res.Strategies {
+				_, _ = fmt.Fprintf(w, "<td>%s</td>", st.Results[mi].Text)
+			}
+			_, _ = w.WriteString("</tr>\n")
+		}
+		_, _ = w.WriteString("</tbody>\n</table>\n")
+	}
 
 	_, _ = w.WriteString("<table>\n<thead><tr>")
 	_, _ = w.WriteString("<th>Date</th><th>ETF</th><th>Index</th><th>Alpha</th><th>LifeStrategy</th><th>GlidePath</th><th>GlideETF</th>")
 	_, _ = w.WriteString("</tr></thead>\n<tbody>\n")
-	for _, r := range rows {
+	for _, r := range res.Rows {
 		_, _ = fmt.Fprintf(w, "<tr><td>%s</td><td>%.2f</td><td>%.2f</td><td>%.5f</td><td>%.2f</td><td>%.2f</td><td>%.4f</td></tr>\n",
 			r.Date, r.ETF, r.Index, r.Alpha, r.Life, r.Glide, r.Weight)
 	}
 	_, _ = w.WriteString("</tbody>\n</table>\n")
+	if len(csvData) > 0 {
+		_, _ = fmt.Fprintf(w, "<a class=\"download\" download=\"%s\" href=\"data:text/csv;base64,%s\">Download CSV</a>\n",
+			csvFileName(p.ETF, p.Index), base64.StdEncoding.EncodeToString(csvData))
+	}
+	_, _ = w.WriteString(charts.Appendix)
 
 	_, _ = w.WriteString("<script>\n")
 	_, _ = w.WriteString("const labels = [")
-	for i, r := range rows {
+	for i, r := range res.Rows {
 		if i > 0 {
 			_, _ = w.WriteString(",")
 		}
@@ -241,7 +289,7 @@ func writeHTMLReport(path string, etfSymbol string, idxSymbol string, startDate
 	_, _ = w.WriteString("];\n")
 
 	_, _ = w.WriteString("const etfData = [")
-	for i, r := range rows {
+	for i, r := range res.Rows {
 		if i > 0 {
 			_, _ = w.WriteString(",")
 		}
@@ -250,7 +298,7 @@ func writeHTMLReport(path string, etfSymbol string, idxSymbol string, startDate
 	_, _ = w.WriteString("];\n")
 
 	_, _ = w.WriteString("const indexData = [")
-	for i, r := range rows {
+	for i, r := range res.Rows {
 		if i > 0 {
 			_, _ = w.WriteString(",")
 		}
@@ -259,7 +307,7 @@ func writeHTMLReport(path string, etfSymbol string, idxSymbol string, startDate
 	_, _ = w.WriteString("];\n")
 
 	_, _ = w.WriteString("const lifeData = [")
-	for i, r := range rows {
+	for i, r := range res.Rows {
 		if i > 0 {
 			_, _ = w.WriteString(",")
 		}
@@ -268,7 +316,7 @@ func writeHTMLReport(path string, etfSymbol string, idxSymbol string, startDate
 	_, _ = w.WriteString("];\n")
 
 	_, _ = w.WriteString("const glideData = [")
-	for i, r := range rows {
+	for i, r := range res.Rows {
 		if i > 0 {
 			_, _ = w.WriteString(",")
 		}
@@ -277,7 +325,7 @@ func writeHTMLReport(path string, etfSymbol string, idxSymbol string, startDate
 	_, _ = w.WriteString("];\n")
 
 	_, _ = w.WriteString("const alphaData = [")
-	for i, r := range rows {
+	for i, r := range res.Rows {
 		if i > 0 {
 			_, _ = w.WriteString(",")
 		}
@@ -285,89 +333,848 @@ func writeHTMLReport(path string, etfSymbol string, idxSymbol string, startDate
 	}
 	_, _ = w.WriteString("];\n")
 
-	_, _ = w.WriteString("new Chart(document.getElementById('cumChart'),{type:'line',data:{labels:labels,datasets:[")
-	_, _ = w.WriteString("{label:'ETF',data:etfData,borderColor:'#1f77b4',backgroundColor:'rgba(31,119,180,0.1)',tension:0.2},")
-	_, _ = w.WriteString("{label:'Index',data:indexData,borderColor:'#ff7f0e',backgroundColor:'rgba(255,127,14,0.1)',tension:0.2},")
-	_, _ = w.WriteString("{label:'LifeStrategy',data:lifeData,borderColor:'#2ca02c',backgroundColor:'rgba(44,160,44,0.1)',tension:0.2},")
-	_, _ = w.WriteString("{label:'GlidePath',data:glideData,borderColor:'#9467bd',backgroundColor:'rgba(148,103,189,0.1)',tension:0.2}")
-	_, _ = w.WriteString("]},options:{plugins:{legend:{position:'bottom'}},scales:{y:{title:{display:true,text:'Cumulative (base 100)'}}}}});\n")
-	_, _ = w.WriteString("new Chart(document.getElementById('alphaChart'),{type:'bar',data:{labels:labels,datasets:[{label:'Alpha',data:alphaData,backgroundColor:'rgba(220,53,69,0.35)',borderColor:'#dc3545'}]},")
-	_, _ = w.WriteString("options:{plugins:{legend:{position:'bottom'}},scales:{y:{title:{display:true,text:'Monthly alpha'}}}}});\n")
+	for li, line := range charts.Lines {
+		_, _ = fmt.Fprintf(w, "const lineData%d = [", li)
+		for i, r := range res.Rows {
+			if i > 0 {
+				_, _ = w.WriteString(",")
+			}
+			if v, ok := line.Values[r.Date]; ok {
+				_, _ = fmt.Fprintf(w, "%.2f", v)
+			} else {
+				_, _ = w.WriteString("null")
+			}
+		}
+		_, _ = w.WriteString("];\n")
+	}
+
+	for li, line := range charts.YieldLines {
+		_, _ = fmt.Fprintf(w, "const yieldData%d = [", li)
+		for i, r := range res.Rows {
+			if i > 0 {
+				_, _ = w.WriteString(",")
+			}
+			if v, ok := line.Values[r.Date]; ok {
+				_, _ = fmt.Fprintf(w, "%.3f", v)
+			} else {
+				_, _ = w.WriteString("null")
+			}
+		}
+		_, _ = w.WriteString("];\n")
+	}
+
+	for li, line := range charts.RealLines {
+		_, _ = fmt.Fprintf(w, "const realData%d = [", li)
+		for i, r := range res.Rows {
+			if i > 0 {
+				_, _ = w.WriteString(",")
+			}
+			if v, ok := line.Values[r.Date]; ok {
+				_, _ = fmt.Fprintf(w, "%.2f", v)
+			} else {
+				_, _ = w.WriteString("null")
+			}
+		}
+		_, _ = w.WriteString("];\n")
+	}
+
+	for li, line := range charts.StyleLines {
+		_, _ = fmt.Fprintf(w, "const styleData%d = [", li)
+		for i, r := range res.Rows {
+			if i > 0 {
+				_, _ = w.WriteString(",")
+			}
+			if v, ok := line.Values[r.Date]; ok {
+				_, _ = fmt.Fprintf(w, "%.1f", v)
+			} else {
+				_, _ = w.WriteString("null")
+			}
+		}
+		_, _ = w.WriteString("];\n")
+	}
+
+	eventLabels, _ := eventsByLabel(charts.Events, res.Rows, p.Freq)
+	if len(eventLabels) > 0 {
+		js, err := eventsJS(eventLabels)
+		if err != nil {
+			return err
+		}
+		_, _ = w.WriteString(js)
+	}
+
+	_, _ = w.WriteString("const cumChart = new Chart(document.getElementById('cumChart'),{type:'line',data:{labels:labels,datasets:[")
+	for i, name := range charts.Series {
+		if i > 0 {
+			_, _ = w.WriteString(",")
+		}
+		for _, cs := range chartSeries {
+			if cs.Name == name {
+				color := charts.Colors[name]
+				_, _ = fmt.Fprintf(w, "{label:'%s',data:%s,borderColor:'%s',backgroundColor:'%s',tension:0.2}", name, cs.Var, color, fillColor(color, 0.1))
+			}
+		}
+	}
+	for li, line := range charts.Lines {
+		color := linePalette[li%len(linePalette)]
+		_, _ = fmt.Fprintf(w, ",{label:%q,data:lineData%d,borderColor:'%s',backgroundColor:'%s',borderDash:[6,3],tension:0.2,spanGaps:true}",
+			html.EscapeString(line.Label), li, color, fillColor(color, 0.1))
+	}
+	if len(eventLabels) > 0 {
+		_, _ = w.WriteString("]},plugins:[eventLines],options:{plugins:{legend:{position:'bottom'},tooltip:{callbacks:{afterBody:eventTooltip}}},")
+	} else {
+		_, _ = w.WriteString("]},options:{plugins:{legend:{position:'bottom'}},")
+	}
+	cumTitle := "Cumulative (base 100)"
+	if charts.Excess {
+		cumTitle = "Cumulative excess over risk-free (base 100)"
+	}
+	_, _ = fmt.Fprintf(w, "scales:{y:{title:{display:true,text:'%s'}}}}});\n", cumTitle)
+	_, _ = w.WriteString(whatIfJS(res.Rows, p.LifeWeight))
+	if editorPoints >= 2 {
+		weights := make([]float64, len(res.Rows))
+		for i, r := range res.Rows {
+			weights[i] = r.Weight
+		}
+		_, _ = w.WriteString(glideEditorJS(res.Rows, weights, editorPoints, charts.Colors["GlidePath"]))
+	}
+	alphaColor := charts.Colors["Alpha"]
+	_, _ = fmt.Fprintf(w, "new Chart(document.getElementById('alphaChart'),{type:'%s',data:{labels:labels,datasets:[{label:'Alpha',data:alphaData,backgroundColor:'%s',borderColor:'%s'}]},",
+		charts.AlphaStyle, fillColor(alphaColor, 0.35), alphaColor)
+	_, _ = fmt.Fprintf(w, "options:{plugins:{legend:{position:'bottom'}},scales:{y:{title:{display:true,text:'%s alpha'}}}}});\n", p.Freq.Title)
+	if charts.Pair != nil {
+		_, _ = w.WriteString(pairJS(p.ETF, *charts.Pair, res.Rows, alphaColor, charts.AlphaStyle, p.Freq))
+	}
+	if len(charts.YieldLines) > 0 {
+		_, _ = w.WriteString("new Chart(document.getElementById('yieldChart'),{type:'line',data:{labels:labels,datasets:[")
+		for li, line := range charts.YieldLines {
+			if li > 0 {
+				_, _ = w.WriteString(",")
+			}
+			color := linePalette[li%len(linePalette)]
+			_, _ = fmt.Fprintf(w, "{label:%q,data:yieldData%d,borderColor:'%s',backgroundColor:'%s',tension:0.2,spanGaps:true}",
+				html.EscapeString(line.Label), li, color, fillColor(color, 0.1))
+		}
+		_, _ = w.WriteString("]},options:{plugins:{legend:{position:'bottom'}},scales:{y:{title:{display:true,text:'Yield (%)'}}}}});\n")
+	}
+	if len(charts.RealLines) > 0 {
+		_, _ = w.WriteString("new Chart(document.getElementById('realChart'),{type:'line',data:{labels:labels,datasets:[")
+		for li, cs := range chartSeries {
+			color := charts.Colors[cs.Name]
+			_, _ = fmt.Fprintf(w, "{label:'%s',data:%s,borderColor:'%s',backgroundColor:'%s',tension:0.2},", cs.Name, cs.Var, color, fillColor(color, 0.1))
+			_, _ = fmt.Fprintf(w, "{label:%q,data:realData%d,borderColor:'%s',backgroundColor:'%s',borderDash:[6,3],tension:0.2,spanGaps:true}",
+				html.EscapeString(charts.RealLines[li].Label), li, color, fillColor(color, 0.1))
+			if li < len(chartSeries)-1 {
+				_, _ = w.WriteString(",")
+			}
+		}
+		_, _ = w.WriteString("]},options:{plugins:{legend:{position:'bottom'}},scales:{y:{title:{display:true,text:'Cumulative (base 100)'}}}}});\n")
+	}
+	if len(charts.StyleLines) > 0 {
+		_, _ = w.WriteString("new Chart(document.getElementById('styleChart'),{type:'line',data:{labels:labels,datasets:[")
+		for li, line := range charts.StyleLines {
+			if li > 0 {
+				_, _ = w.WriteString(",")
+			}
+			color := linePalette[li%len(linePalette)]
+			_, _ = fmt.Fprintf(w, "{label:%q,data:styleData%d,borderColor:'%s',backgroundColor:'%s',fill:true,pointRadius:0,spanGaps:false}",
+				html.EscapeString(line.Label), li, color, fillColor(color, 0.5))
+		}
+		_, _ = w.WriteString("]},options:{plugins:{legend:{position:'bottom'}},scales:{y:{stacked:true,min:0,max:100,title:{display:true,text:'Weight (%)'}}}}});\n")
+	}
+	if len(charts.Sweep) > 0 {
+		_, _ = w.WriteString(sweepJS(charts.Sweep))
+	}
+	if len(charts.Overlay) > 0 {
+		_, _ = w.WriteString(overlayJS(charts.Overlay, charts.OverlayName, p.Freq))
+	}
+	if len(charts.Frontier) > 0 {
+		_, _ = w.WriteString(frontierJS(charts.Frontier, charts.Marks))
+	}
 	_, _ = w.WriteString("</script>\n")
 	_, _ = w.WriteString("</div>\n</body>\n</html>\n")
 
-	return absPath, nil
+	return w.Flush()
 }
 
 func main() {
+	os.Exit(run())
+}
+
+// run parses the flags and writes the reports. It returns the exit status instead of calling
+// os.Exit so deferred cleanup, such as stopping the profiles, runs first.
+func run() int {
 	var (
-		etfSymbol  string
-		idxSymbol  string
-		startDate  string
-		interval   string
-		outPath    string
-		htmlPath   string
-		lifeWeight float64
-		glideStart float64
-		glideEnd   float64
-		verify     bool
+		etfSymbol   string
+		etf2Symbol  string
+		idxSymbol   string
+		startDate   string
+		endDate     string
+		periodName  string
+		interval    string
+		outPath     string
+		htmlPath    string
+		latexPath   string
+		uploadDest  string
+		chartJSSrc  string
+		seriesList  string
+		colorList   string
+		eventsPath  string
+		peerCount   int
+		dividends   bool
+		yieldPeer   string
+		addDists    bool
+		freqName    string
+		fillMode    string
+		fillMaxGap  int
+		etfExch     string
+		idxExch     string
+		rateLimit   float64
+		rateBurst   int
+		cbFailures  int
+		cbCooldown  time.Duration
+		truncCommon bool
+		columnList  string
+		dateFormat  string
+		appendMode  bool
+		cpuProfile  string
+		memProfile  string
+		metricList  string
+		deriveList  string
+		sweepStep   float64
+		frontier    bool
+		target      float64
+		contrib     float64
+		lifeSwitch  string
+		withdrawal  float64
+		rollYears   int
+		rebalReport bool
+		benchList   string
+		styleWindow int
+		driftLimit  float64
+		factorsPath string
+		riskFree    float64
+		rfSymbol    string
+		excessMode  bool
+		mar         float64
+		cpiSource   string
+		savingsList string
+		savingsGoal float64
+		lifeWeight  float64
+		glideStart  float64
+		glideEnd    float64
+		glidePath   string
+		ageGlide    AgeGlide
+		tentDate    string
+		bondTent    BondTent
+		weightsPath string
+		overrides   map[time.Time]float64
+		txPath      string
+		txs         []Transaction
+		broker      string
+		symbolMap   string
+		alertAlpha  string
+		alertTE     string
+		regimeConf  float64
+		badgePath   string
+		icsPath     string
+		icsCount    int
+		covPath     string
+		covSymbols  []string
+		covDates    [][]time.Time
+		covRets     [][]float64
+		optList     string
+		optSymbols  []string
+		optTarget   OptTarget
+		optCons     OptConstraints
+		optViewList string
+		optViews    map[int]float64
+		optConf     float64
+		overlayList string
+		overlayName string
+		windows     []Window
+		roundStep   float64
+		ddCap       float64
+		provider    string
+		fallback    string
+		avKey       string
+		cacheDir    string
+		cacheTTL    time.Duration
+		noCache     bool
+		verify      bool
 	)
+	charts := defaultChartOptions()
 
-	flag.StringVar(&etfSymbol, "etf", "SPY", "ETF symbol")
-	flag.StringVar(&idxSymbol, "index", "^990100-USD-STRD", "Reference index symbol")
+	flag.StringVar(&etfSymbol, "etf", "SPY", "ETF symbol, or NEW<YYYY-MM-DD<OLD to splice a renamed fund")
+	flag.StringVar(&etf2Symbol, "etf2", "", "Second ETF on the same index, e.g. another share class, charted head-to-head with -etf (empty to skip)")
+	flag.StringVar(&idxSymbol, "index", "^990100-USD-STRD", "Reference index symbol, or NEW<YYYY-MM-DD<OLD to splice")
 	flag.StringVar(&startDate, "start", "2019-01-01", "Start date (YYYY-MM-DD)")
-	flag.StringVar(&interval, "interval", "1d", "Yahoo interval")
-	flag.StringVar(&outPath, "out", "", "Output CSV path (empty for stdout)")
+	flag.StringVar(&periodName, "period", "", "Start this long before -end or today instead of -start: 1y, 3y, 5y, 10y or max")
+	flag.StringVar(&endDate, "end", "", "End date (YYYY-MM-DD, inclusive; empty for the latest data)")
+	flag.StringVar(&interval, "interval", "1d", "Bar interval: 1d, 1wk or 1mo (or daily, weekly, monthly)")
+	flag.StringVar(&outPath, "out", "", "Output CSV path (empty for stdout; .gz or .zst compresses)")
 	flag.StringVar(&htmlPath, "html", "", "Output HTML report path (empty to skip)")
+	flag.StringVar(&latexPath, "latex", "", "Output LaTeX tables path (empty to skip)")
+	flag.StringVar(&badgePath, "badge", "", "Output SVG badge with the average alpha (empty to skip)")
+	flag.StringVar(&icsPath, "ics", "", "Output iCalendar file of upcoming rebalance dates with target weights (empty to skip)")
+	flag.IntVar(&icsCount, "ics-count", 12, "Number of upcoming rebalance dates in the -ics calendar")
+	flag.StringVar(&covPath, "cov-out", "", "Output CSV of the covariance and correlation matrices of the per-period returns of the ETF, index, -etf2, peers and benchmarks (empty to skip)")
+	flag.StringVar(&uploadDest, "upload", "", "Upload generated files to s3://bucket/prefix/ or gs://bucket/prefix/")
 	flag.Float64Var(&lifeWeight, "life-etf", 0.80, "LifeStrategy ETF weight")
 	flag.Float64Var(&glideStart, "glide-start", 0.90, "Glide path start ETF weight")
 	flag.Float64Var(&glideEnd, "glide-end", 0.60, "Glide path end ETF weight")
+	flag.StringVar(&glidePath, "glide-path", "", "Glide path ETF weights at evenly spaced points, overriding -glide-start and -glide-end (e.g. 0.6,0.6,0.9)")
+	flag.IntVar(&ageGlide.BirthYear, "birth-year", 0, "Derive the glide path from the investor's age, born this year (0 disables)")
+	flag.IntVar(&ageGlide.RetireAge, "retire-age", 67, "Age after which the age-based glide path stops de-risking")
+	flag.Float64Var(&ageGlide.Base, "age-rule", 110, "Age-based ETF weight in percent is this value minus the age")
+	flag.StringVar(&tentDate, "bond-tent", "", "Add a BondTent track next to the glide path, de-risking from its first weight into this retirement date (YYYY-MM-DD) and re-risking after")
+	flag.Float64Var(&bondTent.Depth, "tent-depth", 0.30, "ETF weight removed from the starting glide weight at the bond tent retirement date")
+	flag.Float64Var(&bondTent.Years, "tent-years", 5, "Years the bond tent takes to de-risk before and re-risk after retirement")
+	flag.StringVar(&weightsPath, "weights", "", "CSV of month,weight ETF weights overriding the glide path in those months")
+	flag.StringVar(&txPath, "transactions", "", "CSV of date,symbol,quantity,price[,fees] trades compared with the strategies")
+	flag.StringVar(&broker, "broker", "", "Read -transactions as a broker export: "+brokerNames())
+	flag.StringVar(&symbolMap, "symbol-map", "", "Rename traded symbols as FROM=TO pairs, e.g. ISINs from -broker exports to Yahoo symbols")
+	flag.StringVar(&alertAlpha, "alert-alpha", "", "Alert and exit with status 7 when the latest period's alpha is below this (e.g. -0.005)")
+	flag.StringVar(&alertTE, "alert-te", "", "Alert and exit with status 7 when the trailing one-year tracking error is above this (e.g. 0.02)")
+	flag.Float64Var(&regimeConf, "regimes", 0, "Flag periods where the mean alpha shifted with at least this confidence (e.g. 0.99; 0 disables)")
+	flag.StringVar(&overlayList, "overlay", "", "Overlay a strategy across windows given as FROM:TO date ranges separated by commas (e.g. 2000-01-01:2009-12-31,2010-01-01:2019-12-31)")
+	flag.StringVar(&overlayName, "overlay-series", "GlidePath", "Strategy drawn by -overlay: ETF, Index, LifeStrategy or GlidePath")
+	flag.StringVar(&optList, "optimize", "", "Add the mean-variance optimal portfolio of the ETF, the index and these comma separated symbols as a track")
+	flag.Float64Var(&optTarget.Return, "opt-return", 0, "Annual expected return the -optimize portfolio must reach at the lowest risk (0 for minimum variance)")
+	flag.Float64Var(&optTarget.Risk, "opt-risk", 0, "Annual volatility within which the -optimize portfolio maximizes expected return (0 for minimum variance)")
+	flag.BoolVar(&optCons.NoShort, "opt-no-short", true, "Forbid negative weights in the -optimize portfolio")
+	flag.Float64Var(&optCons.MaxWeight, "opt-max-weight", 1, "Largest weight of any asset in the -optimize portfolio")
+	flag.StringVar(&optViewList, "opt-views", "", "Expected annual returns as SYMBOL=RETURN pairs blended Black-Litterman style with the history for a second -optimize portfolio (e.g. AGG=0.03)")
+	flag.Float64Var(&optConf, "opt-confidence", 0.5, "Confidence in the -opt-views between 0 (ignore) and 1 (impose)")
+	flag.Float64Var(&ddCap, "max-dd", 0, "Find the weights, glide path and -optimize portfolio with the best CAGR whose max drawdown stayed within this (e.g. 0.25; 0 disables)")
+	flag.Float64Var(&roundStep, "round-weights", 0, "Report the effect of rounding glide path and -optimize weights to multiples of this (e.g. 0.05; 0 disables)")
+	flag.StringVar(&chartJSSrc, "chartjs-path", chartJSCDN, "Chart.js script URL or local path referenced by the HTML report")
+	flag.IntVar(&charts.CumHeight, "cum-height", charts.CumHeight, "Cumulative chart height")
+	flag.IntVar(&charts.AlphaHeight, "alpha-height", charts.AlphaHeight, "Alpha chart height")
+	flag.StringVar(&seriesList, "series", strings.Join(charts.Series, ","), "Series plotted on the cumulative chart")
+	flag.StringVar(&colorList, "colors", "", "Chart color overrides as Name=#rrggbb pairs (ETF, Index, LifeStrategy, GlidePath, Alpha)")
+	flag.StringVar(&charts.AlphaStyle, "alpha-style", charts.AlphaStyle, "Alpha chart style: bar or line")
+	flag.StringVar(&eventsPath, "events", "", "CSV of date,label events marked on the cumulative chart")
+	flag.IntVar(&peerCount, "peers", 0, "Add the top N Yahoo similar funds as comparison lines")
+	flag.BoolVar(&dividends, "dividends", false, "Add a trailing 12-month dividend yield section to the HTML report")
+	flag.StringVar(&yieldPeer, "yield-compare", "", "Symbol whose yield is compared with the ETF (default: index)")
+	flag.BoolVar(&addDists, "add-distributions", false, "Add distributions paid during each month back into that month's return")
+	flag.StringVar(&freqName, "frequency", "auto", "Analysis frequency: auto, monthly, weekly or daily")
+	flag.StringVar(&fillMode, "fill", "none", "Fill closes missing on one exchange at daily frequency: none, carry or interpolate")
+	flag.IntVar(&fillMaxGap, "fill-max-gap", 3, "Longest run of missing trading days filled by -fill")
+	flag.StringVar(&etfExch, "etf-exchange", "auto", "ETF exchange calendar: auto, none, NYSE, LSE, MTA or XETRA")
+	flag.StringVar(&idxExch, "index-exchange", "auto", "Index exchange calendar: auto, none, NYSE, LSE, MTA or XETRA")
+	flag.Float64Var(&rateLimit, "rps", 2, "Maximum provider requests per second (0 disables limiting)")
+	flag.IntVar(&rateBurst, "burst", 4, "Provider requests allowed in a burst above -rps")
+	flag.IntVar(&cbFailures, "breaker-failures", 3, "Consecutive provider failures that open the circuit (0 disables)")
+	flag.DurationVar(&cbCooldown, "breaker-cooldown", 30*time.Second, "Wait before probing a provider whose circuit is open")
+	flag.StringVar(&provider, "provider", "yahoo", "Price data provider: "+providerNames())
+	flag.StringVar(&fallback, "fallback-provider", "", "Provider to download prices from while the -provider circuit is open: "+providerNames())
+	flag.StringVar(&avKey, "alphavantage-key", "", "Alpha Vantage API key for -provider or -fallback-provider alphavantage (default: $ALPHAVANTAGE_API_KEY)")
+	flag.StringVar(&cacheDir, "cache-dir", defaultCacheDir(), "Directory caching downloaded price history")
+	flag.DurationVar(&cacheTTL, "cache-ttl", 12*time.Hour, "How long cached price history is reused before only the newer bars are fetched")
+	flag.BoolVar(&noCache, "no-cache", false, "Always download price history, bypassing the cache")
+	flag.BoolVar(&truncCommon, "truncate-common", false, "Restrict the comparison to the date range both symbols have data for")
+	flag.StringVar(&columnList, "columns", defaultColumns, "CSV columns to write, in order")
+	flag.StringVar(&dateFormat, "date-format", "", "Go time layout for CSV dates, e.g. 2006-01-02 or \"Jan 2006\" (default: 2006-01, or ISO week for weekly)")
+	flag.BoolVar(&appendMode, "append", false, "Append only rows newer than the last row already in -out")
+	flag.StringVar(&cpuProfile, "cpuprofile", "", "Write a CPU profile to this file")
+	flag.StringVar(&memProfile, "memprofile", "", "Write a heap profile to this file at exit")
+	flag.StringVar(&metricList, "metrics", "te,ir,sharpe,sortino,calmar,omega,maxdd", "Summary metrics of the ETF against the index ("+strings.Join(metricNames(), ",")+")")
+	flag.StringVar(&deriveList, "derive", "", "Derived series as name=expression pairs separated by ';' over ETF, Index, Alpha, LifeStrategy, GlidePath, Weight, ETFReturn and IndexReturn (e.g. spread=ETF-Index)")
+	flag.Float64Var(&sweepStep, "sweep", 0, "Chart final value, CAGR, volatility and max drawdown for ETF weights from 0 to 1 in this step (0 disables)")
+	flag.BoolVar(&frontier, "frontier", false, "Chart realized volatility against CAGR for ETF weights in 5% steps, marking the LifeStrategy and glide path weights")
+	flag.Float64Var(&target, "target", 0, "Find the ETF weight and glide path that reached this final value (starting from 100) with the lowest max drawdown (0 disables)")
+	flag.Float64Var(&contrib, "contribution", 0, "Amount added at the start of every period when solving for -target or before the -lifecycle switch")
+	flag.StringVar(&lifeSwitch, "lifecycle", "", "Simulate contributing until this date (YYYY-MM-DD) and withdrawing after it, starting from 100")
+	flag.Float64Var(&withdrawal, "withdrawal", 0, "Amount taken at the start of every period after the -lifecycle switch")
+	flag.IntVar(&rollYears, "rolling-years", 0, "Report the distribution of final values of each strategy over all rolling windows of this many years (0 disables)")
+	flag.BoolVar(&rebalReport, "rebalance-report", false, "Compare the LifeStrategy blend rebalanced every period with buy-and-hold from the same weights")
+	flag.StringVar(&benchList, "benchmarks", "", "Comma separated benchmark symbols for returns-based style analysis of the ETF")
+	flag.IntVar(&styleWindow, "style-window", 36, "Periods in each rolling style analysis window")
+	flag.Float64Var(&driftLimit, "style-drift", 0.2, "Flag style drift when rolling benchmark weights shift by more than this fraction against the previous window")
+	flag.StringVar(&factorsPath, "factors", "", "Fama-French or AQR factor CSV (percent, YYYYMM or YYYYMMDD rows) to regress alpha on")
+	flag.Float64Var(&riskFree, "risk-free", 0, "Annual risk-free rate for Sharpe, Sortino and CAPM alpha (e.g. 0.03)")
+	flag.StringVar(&rfSymbol, "risk-free-symbol", "", "Yahoo yield symbol quoted in percent per year (e.g. ^IRX) for the risk-free rate; -risk-free fills periods it does not cover")
+	flag.BoolVar(&excessMode, "excess", false, "Compute every return and cumulative track in excess of the risk-free rate")
+	flag.Float64Var(&mar, "mar", 0, "Annual minimum acceptable return for downside deviation and threshold of the Omega ratio")
+	flag.StringVar(&cpiSource, "cpi", "", "Deflate returns by a CPI series: EUCPI (Eurostat euro area HICP), a FRED series id such as CPIAUCSL, or csv:file with date,value rows")
+	flag.StringVar(&savingsList, "savings", "", "Comma separated contribution amounts per period for the years-to-target table (needs -savings-target)")
+	flag.Float64Var(&savingsGoal, "savings-target", 0, "Portfolio value the -savings contributions must reach")
 	flag.BoolVar(&verify, "verify", false, "Print sample verification rows to stderr")
 	flag.Parse()
 
+	stopProfiling, err := startProfiling(cpuProfile, memProfile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer stopProfiling()
+
+	if periodName != "" {
+		startSet := false
+		flag.Visit(func(f *flag.Flag) { startSet = startSet || f.Name == "start" })
+		if startSet {
+			fmt.Fprintln(os.Stderr, "Set at most one of -start and -period")
+			return 1
+		}
+		ref := clock.Now()
+		if end, err := time.Parse("2006-01-02", endDate); err == nil {
+			ref = end
+		}
+		if startDate, err = periodStart(periodName, ref); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
 	if err := parseDate(startDate); err != nil {
 		fmt.Fprintf(os.Stderr, "Invalid start date %q: %v\n", startDate, err)
-		os.Exit(1)
+		return 1
+	}
+	var endTime time.Time
+	if endDate != "" {
+		endTime, err = time.Parse("2006-01-02", endDate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid end date %q: %v\n", endDate, err)
+			return 1
+		}
+		if endDate <= startDate {
+			fmt.Fprintf(os.Stderr, "End date %s is not after start date %s\n", endDate, startDate)
+			return 1
+		}
+	}
+	selectedMetrics, err := parseMetrics(metricList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	columns, err := parseColumns(columnList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	savingsAmounts, err := parseAmounts(savingsList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(savingsAmounts) > 0 && savingsGoal <= 0 {
+		fmt.Fprintln(os.Stderr, "-savings needs a positive -savings-target")
+		return 1
+	}
+	derived, err := parseDerived(deriveList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, d := range derived {
+		columns = append(columns, derivedColumn(d))
+	}
+	etfSpec, err := parseSplice(etfSymbol)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	idxSpec, err := parseSplice(idxSymbol)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	etfSymbol, idxSymbol = etfSpec.Symbols[0], idxSpec.Symbols[0]
+
+	normInterval, err := normalizeInterval(interval)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	interval = normInterval
+	freq, err := selectFrequency(freqName, interval)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	etfCal, err := calendarFor(etfExch, etfSymbol)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	idxCal, err := calendarFor(idxExch, idxSymbol)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if fillMode != "none" && fillMode != "carry" && fillMode != "interpolate" {
+		fmt.Fprintf(os.Stderr, "Invalid fill mode %q (want none, carry or interpolate)\n", fillMode)
+		return 1
+	}
+	if err := validateWeight("sweep", sweepStep); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if overlayList != "" {
+		windows, err = parseWindows(overlayList)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		known := false
+		for _, cs := range chartSeries {
+			known = known || cs.Name == overlayName
+		}
+		if !known {
+			fmt.Fprintf(os.Stderr, "Invalid overlay-series %q (want ETF, Index, LifeStrategy or GlidePath)\n", overlayName)
+			return 1
+		}
+	}
+	if ddCap < 0 || ddCap >= 1 {
+		fmt.Fprintln(os.Stderr, "max-dd must be between 0 and 1")
+		return 1
+	}
+	if roundStep != 0 {
+		if err := validateRoundStep(roundStep); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if optViewList != "" && optList == "" {
+		fmt.Fprintln(os.Stderr, "Views only apply to -optimize; ignored")
+	}
+	if optList != "" {
+		optSymbols = []string{etfSymbol, idxSymbol}
+		for _, sym := range parseSymbols(optList) {
+			if !containsFold(optSymbols, sym) {
+				optSymbols = append(optSymbols, sym)
+			}
+		}
+		if optTarget.Return != 0 && optTarget.Risk != 0 {
+			fmt.Fprintln(os.Stderr, "Set at most one of -opt-return and -opt-risk")
+			return 1
+		}
+		if optTarget.Risk < 0 {
+			fmt.Fprintln(os.Stderr, "opt-risk must not be negative")
+			return 1
+		}
+		if err := optCons.validate(len(optSymbols)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if optViewList != "" {
+			if optViews, err = parseViews(optViewList, optSymbols); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			if optConf <= 0 || optConf > 1 {
+				fmt.Fprintln(os.Stderr, "opt-confidence must be above 0 and at most 1")
+				return 1
+			}
+		}
+	}
+	if icsPath != "" && icsCount <= 0 {
+		fmt.Fprintln(os.Stderr, "ics-count must be positive")
+		return 1
+	}
+	if err := validateWeight("regimes", regimeConf); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
 	if err := validateWeight("life-etf", lifeWeight); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return 1
 	}
 	if err := validateWeight("glide-start", glideStart); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return 1
 	}
 	if err := validateWeight("glide-end", glideEnd); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return 1
+	}
+	glide := []float64{glideStart, glideEnd}
+	if glidePath != "" {
+		glide, err = parseGlidePath(glidePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if ageGlide.BirthYear != 0 {
+		if err := validateAgeGlide(ageGlide, clock.Now()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if weightsPath != "" {
+		overrides, err = loadWeightOverrides(weightsPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if txPath != "" {
+		if broker != "" {
+			txs, err = loadBrokerTransactions(txPath, broker)
+		} else {
+			txs, err = loadTransactions(txPath)
+		}
+		if err == nil && symbolMap != "" {
+			err = mapSymbols(txs, symbolMap)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	minAlpha, err := parseThreshold("alert-alpha", alertAlpha)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	maxTE, err := parseThreshold("alert-te", alertTE)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var lifecycleAt time.Time
+	if lifeSwitch != "" {
+		lifecycleAt, err = time.Parse("2006-01-02", lifeSwitch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid lifecycle date %q: %v\n", lifeSwitch, err)
+			return 1
+		}
+		if contrib < 0 || withdrawal < 0 {
+			fmt.Fprintln(os.Stderr, "contribution and withdrawal must not be negative")
+			return 1
+		}
+	}
+	if tentDate != "" {
+		bondTent.Retire, err = time.Parse("2006-01-02", tentDate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid bond-tent date %q: %v\n", tentDate, err)
+			return 1
+		}
+		if err := validateBondTent(bondTent, glide[0]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	series, err := parseChartSeries(seriesList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	charts.Series = series
+	if err := parseChartColors(colorList, charts.Colors); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if charts.AlphaStyle != "bar" && charts.AlphaStyle != "line" {
+		fmt.Fprintf(os.Stderr, "Invalid alpha-style %q (want bar or line)\n", charts.AlphaStyle)
+		return 1
+	}
+	if charts.CumHeight <= 0 || charts.AlphaHeight <= 0 {
+		fmt.Fprintln(os.Stderr, "Chart heights must be positive")
+		return 1
+	}
+	if eventsPath != "" {
+		events, err := loadEvents(eventsPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		charts.Events = events
+	}
+	var factors FactorSet
+	if factorsPath != "" {
+		factors, err = loadFactors(factorsPath)
+		if err == nil {
+			err = checkFactorFrequency(factors, freq)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if !strings.Contains(chartJSSrc, "://") {
+		if _, err := outputFS.Stat(chartJSSrc); err != nil {
+			fmt.Fprintf(os.Stderr, "Chart.js path %q: %v\n", chartJSSrc, err)
+		}
+		chartJSSrc = filepath.ToSlash(chartJSSrc)
+	}
+	if err := validateUploadDest(uploadDest); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
 
-	query := yahoofinanceapi.HistoryQuery{
-		Start:    startDate,
-		Interval: interval,
+	if avKey == "" {
+		avKey = os.Getenv("ALPHAVANTAGE_API_KEY")
+	}
+	selected, err := selectProvider(provider, avKey)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	provider = strings.ToLower(provider)
+	priceProvider = selected
+	if fallback != "" {
+		if strings.EqualFold(fallback, provider) {
+			fmt.Fprintf(os.Stderr, "-fallback-provider %s is already the main provider\n", fallback)
+			return 1
+		}
+		fallbackProvider, err = selectProvider(fallback, avKey)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	providerLimiter = NewTokenBucket(rateLimit, rateBurst)
+	providerBreaker = NewCircuitBreaker(provider, cbFailures, cbCooldown)
+	if !noCache && cacheDir != "" && cacheTTL > 0 {
+		// Yahoo keeps the top of the cache directory; other providers get their own.
+		if provider != "yahoo" {
+			cacheDir = filepath.Join(cacheDir, provider)
+		}
+		historyCache = &HistoryCache{Dir: cacheDir, TTL: cacheTTL}
 	}
 
-	etfSeries, err := loadFromYahoo(etfSymbol, query)
+	start, _ := time.Parse("2006-01-02", startDate)
+	query := PriceQuery{Start: start, End: endTime, Interval: interval}
+	asOf := clock.Now()
+	if endDate != "" {
+		asOf = endTime.AddDate(0, 0, 1)
+	}
+
+	load := func(symbol string) (Series, error) { return loadPrices(symbol, query) }
+	etfSeries, etfSplices, err := loadSpliced(etfSpec, load)
+	if err == nil && len(etfSeries.Points) == 0 {
+		err = fmt.Errorf("%s: %w", etfSymbol, ErrNoData)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ETF error: %v\n", err)
-		os.Exit(1)
+		return exitCode(err)
+	}
+	idxSeries, idxSplices, err := loadSpliced(idxSpec, load)
+	if err == nil && len(idxSeries.Points) == 0 {
+		err = fmt.Errorf("%s: %w", idxSymbol, ErrNoData)
 	}
-	idxSeries, err := loadFromYahoo(idxSymbol, query)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Index error: %v\n", err)
-		os.Exit(1)
+		return exitCode(err)
+	}
+	if endDate != "" {
+		etfSeries = truncateSeries(etfSeries, etfSeries.Points[0].Date, endTime)
+		idxSeries = truncateSeries(idxSeries, idxSeries.Points[0].Date, endTime)
+	}
+	charts.Events = append(charts.Events, etfSplices...)
+	charts.Events = append(charts.Events, idxSplices...)
+
+	if len(etfSeries.Points) > 0 && len(idxSeries.Points) > 0 {
+		firstE, lastE := seriesRange(etfSeries)
+		firstI, lastI := seriesRange(idxSeries)
+		for _, c := range []struct {
+			symbol string
+			last   time.Time
+		}{{etfSymbol, lastE}, {idxSymbol, lastI}} {
+			if asOf.Sub(c.last) > staleAfter(interval) {
+				fmt.Fprintf(os.Stderr, "Warning: %s has no data after %s (delisted or merged?)\n", c.symbol, c.last.Format("2006-01-02"))
+			}
+		}
+		if truncCommon {
+			from, to := firstE, lastE
+			if firstI.After(from) {
+				from = firstI
+			}
+			if lastI.Before(to) {
+				to = lastI
+			}
+			etfSeries = truncateSeries(etfSeries, from, to)
+			idxSeries = truncateSeries(idxSeries, from, to)
+			fmt.Fprintf(os.Stderr, "Comparison window truncated to common range %s..%s\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
+		}
 	}
 
-	etfMonthly := monthlySeries(etfSeries.Points)
-	idxMonthly := monthlySeries(idxSeries.Points)
+	if interval == "1d" {
+		for _, c := range []struct {
+			series Series
+			cal    *ExchangeCalendar
+		}{{etfSeries, etfCal}, {idxSeries, idxCal}} {
+			if c.cal == nil {
+				continue
+			}
+			check := checkCalendar(c.series, c.cal)
+			fmt.Fprintf(os.Stderr, "Calendar %s (%s): %d/%d trading days present", c.series.Symbol, c.cal.Name, check.Expected-len(check.Missing), check.Expected)
+			if len(check.Missing) > 0 {
+				fmt.Fprintf(os.Stderr, ", missing e.g. %s", check.Missing[0].Format("2006-01-02"))
+			}
+			if check.ClosedBars > 0 {
+				fmt.Fprintf(os.Stderr, ", %d bars on closed days", check.ClosedBars)
+			}
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+
+	if fillMode != "none" {
+		if freq.Name != "daily" {
+			fmt.Fprintln(os.Stderr, "Gap filling only applies to -frequency daily; ignored")
+		} else {
+			var filledE, filledI FillStats
+			etfSeries, idxSeries, filledE, filledI = fillGaps(etfSeries, idxSeries, etfCal, idxCal, fillMode, fillMaxGap)
+			fmt.Fprintf(os.Stderr, "Gap fill (%s, max %d days): %s %d closed + %d missing days, %s %d closed + %d missing days\n",
+				fillMode, fillMaxGap, etfSymbol, filledE.Closed, filledE.Missing, idxSymbol, filledI.Closed, filledI.Missing)
+		}
+	}
+
+	if freq.Name == "weekly" {
+		fmt.Fprintln(os.Stderr, "Interval 1wk: running the analysis on ISO weeks")
+	}
+	etfMonthly := periodSeries(etfSeries.Points, freq)
+	idxMonthly := periodSeries(idxSeries.Points, freq)
+
+	var etfDivs, idxDivs map[time.Time]float64
+	if addDists || dividends {
+		divs, err := fetchDividends(etfSymbol, startDate, endDate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Dividends error: %v\n", err)
+		} else {
+			etfDivs = periodDividends(divs, freq)
+		}
+	}
+	if addDists {
+		divs, err := fetchDividends(idxSymbol, startDate, endDate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Dividends error: %v\n", err)
+		} else {
+			idxDivs = periodDividends(divs, freq)
+		}
+		fmt.Fprintf(os.Stderr, "Distributions added back: %s %d months, %s %d months\n", etfSymbol, len(etfDivs), idxSymbol, len(idxDivs))
+	}
 
-	datesE, retsE := monthlyReturns(etfMonthly)
-	datesI, retsI := monthlyReturns(idxMonthly)
+	var retDivsE, retDivsI map[time.Time]float64
+	if addDists {
+		retDivsE, retDivsI = etfDivs, idxDivs
+	}
+	datesE, retsE := monthlyReturns(etfMonthly, retDivsE)
+	datesI, retsI := monthlyReturns(idxMonthly, retDivsI)
 
 	alignedDates, alignedE, alignedI := alignReturns(datesE, retsE, datesI, retsI)
+	covSymbols = append(covSymbols, etfSymbol, idxSymbol)
+	covDates = append(covDates, datesE, datesI)
+	covRets = append(covRets, retsE, retsI)
 	if len(alignedDates) == 0 {
 		fmt.Fprintln(os.Stderr, "No aligned months. Check symbols or date range.")
-		os.Exit(1)
+		return exitCode(ErrAlignment)
+	}
+
+	rfRates := make(map[time.Time]float64)
+	if rfSymbol != "" {
+		rfSeries, err := loadPrices(rfSymbol, query)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Risk-free %s skipped: %v\n", rfSymbol, err)
+		} else {
+			rfRates = riskFreeRates(periodSeries(rfSeries.Points, freq), freq.PeriodsPerYear)
+		}
+	}
+	alignedRF, rfMissing := riskFreeFor(alignedDates, rfRates, periodRate(riskFree, freq.PeriodsPerYear))
+	if rfSymbol != "" && rfMissing > 0 {
+		fmt.Fprintf(os.Stderr, "Risk-free %s: %d of %d periods use the -risk-free rate\n", rfSymbol, rfMissing, len(alignedDates))
 	}
 
 	if verify {
@@ -379,7 +1186,7 @@ func main() {
 			idxClose := idxMonthly[d]
 			alpha := alignedE[i] - alignedI[i]
 			fmt.Fprintf(os.Stderr, "%s,%.2f,%.2f,%.5f,%.5f,%.5f\n",
-				d.Format("2006-01"),
+				freq.Label(d),
 				etfClose,
 				idxClose,
 				alignedE[i],
@@ -403,93 +1210,365 @@ func main() {
 		}
 	}
 
-	lifeRets := blendReturns(alignedE, alignedI, lifeWeight)
-	glideWeights := glideWeights(len(alignedDates), glideStart, glideEnd)
-	glideRets := make([]float64, len(alignedDates))
-	for i := range alignedDates {
-		glideRets[i] = alignedE[i]*glideWeights[i] + alignedI[i]*(1-glideWeights[i])
+	if peerCount > 0 {
+		peers, err := fetchPeers(etfSymbol, peerCount)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Peers error: %v\n", err)
+		}
+		for _, peer := range peers {
+			peerSeries, err := loadPrices(peer, query)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Peer %s skipped: %v\n", peer, err)
+				continue
+			}
+			datesP, retsP := monthlyReturns(periodSeries(peerSeries.Points, freq), nil)
+			_, peerE, peerI := alignReturns(datesP, retsP, alignedDates, alignedI)
+			if len(peerE) == 0 {
+				fmt.Fprintf(os.Stderr, "Peer %s skipped: no aligned months\n", peer)
+				continue
+			}
+			peerAlpha := 0.0
+			for i := range peerE {
+				peerAlpha += peerE[i] - peerI[i]
+			}
+			fmt.Fprintf(os.Stderr, "Peer %s: avg alpha vs index=%.5f over %d months\n", peer, peerAlpha/float64(len(peerE)), len(peerE))
+			charts.Lines = append(charts.Lines, peerLine(peer, alignedDates, datesP, retsP, freq))
+			covSymbols = append(covSymbols, peer)
+			covDates = append(covDates, datesP)
+			covRets = append(covRets, retsP)
+		}
 	}
 
-	cumE := cumulative(100, alignedE)
-	cumI := cumulative(100, alignedI)
-	cumLife := cumulative(100, lifeRets)
-	cumGlide := cumulative(100, glideRets)
-
-	var out *os.File
-	if outPath != "" {
-		f, err := os.Create(outPath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Cannot create output file: %v\n", err)
-			os.Exit(1)
+	if benchmarks := parseSymbols(benchList); len(benchmarks) > 0 {
+		benchDates := make([][]time.Time, 0, len(benchmarks))
+		benchRets := make([][]float64, 0, len(benchmarks))
+		loaded := make([]string, 0, len(benchmarks))
+		for _, b := range benchmarks {
+			s, err := loadPrices(b, query)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Benchmark %s skipped: %v\n", b, err)
+				continue
+			}
+			d, r := monthlyReturns(periodSeries(s.Points, freq), nil)
+			benchDates = append(benchDates, d)
+			benchRets = append(benchRets, r)
+			loaded = append(loaded, b)
+			covSymbols = append(covSymbols, b)
+			covDates = append(covDates, d)
+			covRets = append(covRets, r)
 		}
-		defer func() {
-			if cerr := f.Close(); cerr != nil {
-				fmt.Fprintf(os.Stderr, "Failed to close output file: %v\n", cerr)
+		styleDates, styleY, styleX := alignBenchmarks(alignedDates, alignedE, benchDates, benchRets)
+		switch {
+		case len(loaded) == 0:
+		case len(styleDates) < 2:
+			fmt.Fprintln(os.Stderr, "Style analysis skipped: benchmarks share no common periods with the ETF")
+		default:
+			fmt.Fprintf(os.Stderr, "Style analysis over %d periods: %s\n", len(styleDates), formatWeights(loaded, styleWeights(styleY, styleX)))
+			if len(styleDates) >= styleWindow && styleWindow > 1 {
+				windows := rollingStyle(styleDates, styleY, styleX, styleWindow)
+				charts.StyleLines = styleLines(loaded, windows, freq)
+				for _, d := range styleDrifts(windows, styleWindow, driftLimit) {
+					fmt.Fprintf(os.Stderr, "ALERT: style drift of %.1f%% by %s: %s -> %s\n", d.Shift*100, freq.Label(d.Date),
+						formatWeights(loaded, d.From), formatWeights(loaded, d.To))
+					charts.Events = append(charts.Events, ChartEvent{Date: d.Date, Label: "Style drift"})
+				}
 			}
-		}()
-		out = f
-	} else {
-		out = os.Stdout
+		}
 	}
 
-	writer := bufio.NewWriter(out)
-	defer func() {
-		if err := writer.Flush(); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to flush output: %v\n", err)
+	if dividends {
+		if yieldPeer == "" {
+			yieldPeer = idxSymbol
+		}
+		if etfDivs != nil {
+			charts.YieldLines = append(charts.YieldLines, trailingYield(etfSymbol, alignedDates, etfMonthly, etfDivs, freq))
 		}
-	}()
 
-	_, _ = writer.WriteString("Date,ETF,Index,Alpha,LifeStrategy,GlidePath,GlideEtfWeight\n")
+		peerDivs, err := fetchDividends(yieldPeer, startDate, endDate)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Dividends error: %v\n", err)
+		case len(peerDivs) == 0:
+			fmt.Fprintf(os.Stderr, "Dividends: %s has no distributions, yield not compared\n", yieldPeer)
+		default:
+			peerCloses := idxMonthly
+			if yieldPeer != idxSymbol {
+				peerSeries, err := loadPrices(yieldPeer, query)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Dividends error: %v\n", err)
+					break
+				}
+				peerCloses = periodSeries(peerSeries.Points, freq)
+			}
+			charts.YieldLines = append(charts.YieldLines, trailingYield(yieldPeer, alignedDates, peerCloses, periodDividends(peerDivs, freq), freq))
+		}
+	}
 
-	validCount := 0
-	winCount := 0
-	sumAlpha := 0.0
-	rows := make([]ReportRow, 0, len(alignedDates))
-	for i, d := range alignedDates {
-		alpha := alignedE[i] - alignedI[i]
-		if math.IsNaN(alpha) || math.IsInf(alpha, 0) {
-			continue
+	var realE, realI []float64
+	if cpiSource != "" {
+		levels, err := loadCPI(cpiSource)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
 		}
-		validCount++
-		if alpha > 0 {
-			winCount++
-		}
-		sumAlpha += alpha
-
-		_, _ = fmt.Fprintf(writer, "%s,%.2f,%.2f,%.5f,%.2f,%.2f,%.4f\n",
-			d.Format("2006-01"),
-			cumE[i],
-			cumI[i],
-			alpha,
-			cumLife[i],
-			cumGlide[i],
-			glideWeights[i],
-		)
-
-		rows = append(rows, ReportRow{
-			Date:   d.Format("2006-01"),
-			ETF:    cumE[i],
-			Index:  cumI[i],
-			Alpha:  alpha,
-			Life:   cumLife[i],
-			Glide:  cumGlide[i],
-			Weight: glideWeights[i],
-		})
+		inflation := monthlyInflation(levels)
+		realE = append([]float64(nil), alignedE...)
+		realI = append([]float64(nil), alignedI...)
+		missing := deflateReturns(alignedDates, realE, inflation, freq)
+		deflateReturns(alignedDates, realI, inflation, freq)
+		if missing > 0 {
+			fmt.Fprintf(os.Stderr, "CPI %s: %d of %d periods have no inflation data and stay nominal\n", cpiSource, missing, len(alignedDates))
+		}
+	}
+	if excessMode {
+		subtractRiskFree(alignedE, alignedRF)
+		subtractRiskFree(alignedI, alignedRF)
+		if realE != nil {
+			subtractRiskFree(realE, alignedRF)
+			subtractRiskFree(realI, alignedRF)
+		}
+		charts.Excess = true
 	}
 
-	if validCount == 0 {
+	// In excess mode the returns already have the risk-free rate taken out.
+	metricsRF := alignedRF
+	if excessMode {
+		metricsRF = nil
+	}
+	res, err := Analyze(Params{
+		ETF:        etfSymbol,
+		Index:      idxSymbol,
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Interval:   interval,
+		Freq:       freq,
+		LifeWeight: lifeWeight,
+		Glide:      glide,
+		Age:        ageGlide,
+		Tent:       bondTent,
+		Overrides:  overrides,
+		Metrics:    selectedMetrics,
+		MAR:        mar,
+	}, alignedDates, alignedE, alignedI, metricsRF)
+	if err != nil {
 		fmt.Fprintln(os.Stderr, "No valid months for ETF vs index comparison.")
-		os.Exit(1)
+		return exitCode(err)
+	}
+	if weightsPath != "" {
+		fmt.Fprintf(os.Stderr, "Weight overrides: %d of %d periods\n", res.Overridden, len(alignedDates))
+	}
+	if res.TentReturns != nil {
+		charts.Lines = append(charts.Lines, peerLine("BondTent", alignedDates, res.Dates, res.TentReturns, freq))
+	}
+	if roundStep > 0 {
+		rounded := roundGlide(res.GlideWeights, roundStep)
+		fmt.Fprintf(os.Stderr, "Rounded GlidePath: %d weight changes instead of %d\n", weightChanges(rounded), weightChanges(res.GlideWeights))
+		writeRoundingReport(os.Stderr, "GlidePath", res.GlideReturns, weightedReturns(res.ETFReturns, res.IndexReturns, rounded), freq.PeriodsPerYear)
+	}
+	if etf2Symbol != "" {
+		series2, err := loadPrices(etf2Symbol, query)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ETF2 error: %v\n", err)
+			return exitCode(err)
+		}
+		dates2, rets2 := monthlyReturns(periodSeries(series2.Points, freq), nil)
+		covSymbols = append(covSymbols, etf2Symbol)
+		covDates = append(covDates, dates2)
+		covRets = append(covRets, rets2)
+		pairRets := rets2
+		if excessMode {
+			rf := make(map[time.Time]float64, len(alignedDates))
+			for i, d := range alignedDates {
+				rf[d] = alignedRF[i]
+			}
+			pairRets = make([]float64, len(rets2))
+			for i, d := range dates2 {
+				pairRets[i] = rets2[i] - rf[d]
+			}
+		}
+		pair := pairedETF(etf2Symbol, res.Rows, dates2, pairRets)
+		if pair.Periods == 0 {
+			fmt.Fprintf(os.Stderr, "ETF2 %s skipped: no aligned periods\n", etf2Symbol)
+		} else {
+			fmt.Fprintf(os.Stderr, "ETF2 %s: avg alpha=%.5f, %s beat it in %d of %d periods by %.5f on average, head-to-head %.2f\n",
+				etf2Symbol, pair.AvgAlpha, etfSymbol, pair.Wins, pair.Periods, pair.AvgDiff, pair.Final)
+			charts.Pair = &pair
+		}
+	}
+	if len(optSymbols) > 0 {
+		optDates := [][]time.Time{datesE, datesI}
+		optRets := [][]float64{retsE, retsI}
+		for _, sym := range optSymbols[2:] {
+			s, err := loadPrices(sym, query)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Optimizer error: %v\n", err)
+				return exitCode(err)
+			}
+			d, r := monthlyReturns(periodSeries(s.Points, freq), nil)
+			optDates = append(optDates, d)
+			optRets = append(optRets, r)
+		}
+		optPeriods, _, cols := alignBenchmarks(alignedDates, alignedE, optDates, optRets)
+		if len(optPeriods) < 2 {
+			fmt.Fprintln(os.Stderr, "Optimizer skipped: fewer than two periods common to all symbols")
+		} else {
+			mu, cov := assetMoments(cols)
+			opts := []OptimalPortfolio{optimizePortfolio("Historical", optSymbols, mu, cov, optTarget, optCons, freq.PeriodsPerYear)}
+			if len(optViews) > 0 {
+				perPeriod := make(map[int]float64, len(optViews))
+				for i, v := range optViews {
+					perPeriod[i] = v / freq.PeriodsPerYear
+				}
+				blended, err := blackLitterman(mu, cov, len(optPeriods), perPeriod, optConf)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Optimizer error: %v\n", err)
+					return 1
+				}
+				opts = append(opts, optimizePortfolio("Views", optSymbols, blended, cov, optTarget, optCons, freq.PeriodsPerYear))
+			}
+			if ddCap > 0 {
+				capped, ok := cappedOptimum("Capped", optSymbols, mu, cov, cols, optCons, ddCap, freq.PeriodsPerYear)
+				if ok {
+					opts = append(opts, capped)
+				} else {
+					fmt.Fprintf(os.Stderr, "Optimizer: no frontier portfolio kept its max drawdown within %.2f%%\n", ddCap*100)
+				}
+			}
+			// The fixed blends are compared over the same periods, which every symbol covers.
+			glideByDate := make(map[time.Time]float64, len(alignedDates))
+			for i, d := range alignedDates {
+				glideByDate[d] = res.GlideWeights[i]
+			}
+			glideWeights := make([]float64, len(optPeriods))
+			for i, d := range optPeriods {
+				glideWeights[i] = glideByDate[d]
+			}
+			writeOptimizerReport(os.Stderr, opts, cols, []TrackReturns{
+				{"LifeStrategy", blendReturns(cols[0], cols[1], lifeWeight)},
+				{"GlidePath", weightedReturns(cols[0], cols[1], glideWeights)},
+			}, freq.PeriodsPerYear)
+			if roundStep > 0 {
+				for _, opt := range opts {
+					rounded := roundWeights(opt.Weights, roundStep)
+					fmt.Fprintf(os.Stderr, "Rounded %s optimum: %s\n", opt.Name, formatWeights(optSymbols, rounded))
+					writeRoundingReport(os.Stderr, opt.Name+" optimum", portfolioReturns(cols, opt.Weights), portfolioReturns(cols, rounded), freq.PeriodsPerYear)
+				}
+			}
+			rf := make(map[time.Time]float64, len(alignedDates))
+			if excessMode {
+				for i, d := range alignedDates {
+					rf[d] = alignedRF[i]
+				}
+			}
+			for _, opt := range opts {
+				track := portfolioReturns(cols, opt.Weights)
+				for i, d := range optPeriods {
+					track[i] -= rf[d]
+				}
+				label := opt.Name + " optimum (" + formatWeights(optSymbols, opt.Weights) + ")"
+				charts.Lines = append(charts.Lines, peerLine(label, alignedDates, optPeriods, track, freq))
+			}
+		}
+	}
+
+	if sweepStep > 0 {
+		charts.Sweep = weightSweep(alignedE, alignedI, sweepStep, freq.PeriodsPerYear)
+	}
+	if rebalReport {
+		writeRebalanceReport(os.Stderr, rebalanceStats(alignedE, alignedI, lifeWeight, freq.PeriodsPerYear), lifeWeight, freq)
+	}
+	// A glide path is stretched over each rolling or overlay window; age-based paths and
+	// weight overrides follow the calendar.
+	glideFor := func(_ int, n int) []float64 { return pathWeights(n, glide) }
+	if ageGlide.BirthYear != 0 || weightsPath != "" {
+		glideFor = func(start int, n int) []float64 { return res.GlideWeights[start : start+n] }
+	}
+	if rollYears > 0 {
+		writeRollingReport(os.Stderr, alignedE, alignedI, rollYears, freq, lifeWeight, glideFor)
+	}
+	if len(windows) > 0 {
+		returnsFor := func(start int, n int) []float64 {
+			a, b := alignedE[start:start+n], alignedI[start:start+n]
+			switch overlayName {
+			case "ETF":
+				return a
+			case "Index":
+				return b
+			case "LifeStrategy":
+				return blendReturns(a, b, lifeWeight)
+			}
+			return weightedReturns(a, b, glideFor(start, n))
+		}
+		charts.Overlay = overlayLines(alignedDates, windows, returnsFor, freq)
+		charts.OverlayName = overlayName
+		if len(charts.Overlay) < len(windows) {
+			fmt.Fprintf(os.Stderr, "Overlay: %d of %d windows have no data\n", len(windows)-len(charts.Overlay), len(windows))
+		}
+	}
+	if ddCap > 0 {
+		writeDrawdownCapReport(os.Stderr, alignedE, alignedI, ddCap, freq.PeriodsPerYear)
+	}
+	if target > 0 {
+		writeSolverReport(os.Stderr, alignedE, alignedI, target, contrib)
+	}
+	if frontier {
+		charts.Frontier = weightSweep(alignedE, alignedI, 0.05, freq.PeriodsPerYear)
+		charts.Marks = []FrontierMark{
+			{"LifeStrategy", sweepPoint(alignedE, alignedI, lifeWeight, freq.PeriodsPerYear)},
+			{"Glide start", sweepPoint(alignedE, alignedI, res.GlideWeights[0], freq.PeriodsPerYear)},
+			{"Glide end", sweepPoint(alignedE, alignedI, res.GlideWeights[len(res.GlideWeights)-1], freq.PeriodsPerYear)},
+		}
+	}
+	strategyRets := [][]float64{res.ETFReturns, res.IndexReturns, res.LifeReturns, res.GlideReturns}
+	if txPath != "" {
+		closes := map[string]map[time.Time]float64{etfSymbol: etfMonthly, idxSymbol: idxMonthly}
+		for _, sym := range transactionSymbols(txs) {
+			if closes[sym] != nil {
+				continue
+			}
+			series, err := loadPrices(sym, query)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Transactions error: %v\n", err)
+				return exitCode(err)
+			}
+			closes[sym] = periodSeries(series.Points, freq)
+		}
+		if excessMode {
+			fmt.Fprintln(os.Stderr, "Transactions: strategies use returns in excess of the risk-free rate")
+		}
+		writeTransactionReport(os.Stderr, actualTrack(txs, alignedDates, closes, freq), strategyRets, len(txs))
+	}
+	if lifeSwitch != "" {
+		lifecycle := lifecycleReport(alignedDates, strategyRets, lifecycleAt, contrib, withdrawal)
+		writeLifecycleReport(os.Stderr, lifecycle, freq)
+		charts.Lifecycle = &lifecycle
+	}
+	if len(savingsAmounts) > 0 {
+		charts.Savings = savingsGrid(strategyRets, savingsAmounts, savingsGoal, freq.PeriodsPerYear)
+		charts.SavingsGoal = savingsGoal
+	}
+	if realE != nil {
+		charts.RealLines, charts.RealTable = realSection(alignedDates, strategyRets,
+			[][]float64{realE, realI, blendReturns(realE, realI, lifeWeight), weightedReturns(realE, realI, res.GlideWeights)}, freq)
+	}
+	for _, d := range derived {
+		charts.Lines = append(charts.Lines, derivedLine(d, res.Rows))
+	}
+
+	// csvData keeps the full CSV so the HTML report can embed it.
+	var csvData bytes.Buffer
+	writeCSV(&csvData, res.Rows, columns, dateFormat, true)
+	if err := writeCSVOutput(outPath, appendMode, csvData.Bytes(), res.Rows, columns, dateFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "CSV output error: %v\n", err)
+		return 1
 	}
 
-	avgAlpha := sumAlpha / float64(validCount)
-	fmt.Fprintf(os.Stderr, "Tracking difference: ETF>index=%d/%d, avg=%.5f\n", winCount, validCount, avgAlpha)
+	fmt.Fprintf(os.Stderr, "Tracking difference: ETF>index=%d/%d, avg=%.5f\n", res.WinCount, res.Total, res.AvgAlpha)
 
-	lastE := cumE[len(cumE)-1]
-	lastI := cumI[len(cumI)-1]
+	lastE, lastI := res.FinalETF, res.FinalIndex
 	if math.IsNaN(lastE) || math.IsNaN(lastI) {
 		fmt.Fprintln(os.Stderr, "Final comparison not available: insufficient data.")
-		os.Exit(1)
+		return 1
 	}
 	result := "equal to"
 	if lastE > lastI {
@@ -498,12 +1577,110 @@ func main() {
 		result = "lower than"
 	}
 	fmt.Fprintf(os.Stderr, "Result: %s is %s index (%.2f vs %.2f)\n", etfSymbol, result, lastE, lastI)
+	writeGlideTurnover(os.Stderr, res.Rows, freq)
+
+	for _, m := range res.Metrics {
+		fmt.Fprintf(os.Stderr, "Metric %s: %s\n", m.Title, m.Text)
+	}
+	if regimeConf > 0 {
+		charts.Regimes = changePoints(res.Rows, max(6, int(freq.PeriodsPerYear/2)), regimeConf)
+		for _, c := range charts.Regimes {
+			fmt.Fprintf(os.Stderr, "Regime change from %s: mean alpha %.5f -> %.5f (confidence %.1f%%)\n", c.Label, c.Before, c.After, c.Confidence*100)
+			charts.Events = append(charts.Events, ChartEvent{Date: c.Date, Label: "Regime change"})
+		}
+	}
+	charts.Alerts = checkAlerts(res.Rows, freq, minAlpha, maxTE)
+	for _, a := range charts.Alerts {
+		fmt.Fprintf(os.Stderr, "ALERT: %s\n", a)
+	}
+
+	if factorsPath != "" {
+		y, xs := alignFactors(alignedDates, activeReturns(MetricInput{Returns: alignedE, Benchmark: alignedI}), factors, freq)
+		fit, err := regressFactors(y, xs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Factor regression skipped: %v\n", err)
+		} else {
+			fit.Names = factors.Names
+			writeFactorReport(os.Stderr, fit, freq)
+		}
+	}
+
+	if latexPath != "" {
+		if err := writeLaTeXTables(latexPath, res); err != nil {
+			fmt.Fprintf(os.Stderr, "LaTeX export error: %v\n", err)
+			return 1
+		}
+	}
+
+	if badgePath != "" {
+		if _, err := writeFileAtomic(badgePath, []byte(alphaBadge(res.AvgAlpha, freq))); err != nil {
+			fmt.Fprintf(os.Stderr, "Badge error: %v\n", err)
+			return 1
+		}
+	}
+
+	if covPath != "" {
+		// Nominal returns on the periods every symbol has, whatever -excess or -cpi are set to.
+		covPeriods, _, cols := alignBenchmarks(alignedDates, alignedE, covDates, covRets)
+		if len(covPeriods) < 2 {
+			fmt.Fprintln(os.Stderr, "Covariance skipped: fewer than two periods common to all symbols")
+		} else {
+			if _, err := writeFileAtomic(covPath, renderCovariance(covSymbols, cols)); err != nil {
+				fmt.Fprintf(os.Stderr, "Covariance error: %v\n", err)
+				return 1
+			}
+			fmt.Fprintf(os.Stderr, "Covariance: %d symbols over %d %s periods\n", len(covSymbols), len(covPeriods), freq.Name)
+		}
+	}
+
+	if icsPath != "" {
+		upcoming := upcomingPeriods(clock.Now(), icsCount, freq)
+		cal := rebalanceCalendar(upcoming, rebalancePlan(upcoming, res.Params), res.Params, clock.Now())
+		if _, err := writeFileAtomic(icsPath, []byte(cal)); err != nil {
+			fmt.Fprintf(os.Stderr, "Calendar error: %v\n", err)
+			return 1
+		}
+	}
 
 	if htmlPath != "" {
-		reportPath, err := writeHTMLReport(htmlPath, etfSymbol, idxSymbol, startDate, interval, lifeWeight, glideStart, glideEnd, rows, avgAlpha, winCount, validCount)
+		if _, skipped := eventsByLabel(charts.Events, res.Rows, freq); skipped > 0 {
+			fmt.Fprintf(os.Stderr, "Events: %d outside the report period were not plotted\n", skipped)
+		}
+		rfDesc := fmt.Sprintf("%.2f%% per year", riskFree*100)
+		if rfSymbol != "" {
+			rfDesc = fmt.Sprintf("%s, or %.2f%% per year where it has no data", rfSymbol, riskFree*100)
+		}
+		charts.Appendix = methodologyHTML(Methodology{
+			Interval:      interval,
+			Freq:          freq,
+			Resampled:     interval != "1mo",
+			Fill:          fillMode,
+			Distributions: addDists,
+			Excess:        excessMode,
+			RiskFree:      rfDesc,
+			CPI:           cpiSource,
+			LifeWeight:    lifeWeight,
+			Glide:         glide,
+			Age:           ageGlide,
+			Tent:          bondTent,
+			Overridden:    res.Overridden,
+			Metrics:       selectedMetrics,
+		})
+		reportPath, err := filepath.Abs(htmlPath)
 		if err != nil {
+			fmt.Fprintf(os.Stderr, "HTML report error: resolve path: %v\n", err)
+			return 1
+		}
+		// The report is built in memory and written atomically so a failed run never
+		// leaves a truncated page behind.
+		var page bytes.Buffer
+		if err := writeHTMLReport(&page, res, csvData.Bytes(), chartJSSrc, charts); err != nil {
 			fmt.Fprintf(os.Stderr, "HTML report error: %v\n", err)
-			os.Exit(1)
+			return 1
+		}
+		if _, err := writeFileAtomic(reportPath, page.Bytes()); err != nil {
+			fmt.Fprintf(os.Stderr, "HTML report error: write html: %v\n", err)
+			return 1
 		}
 
 		cmd := exec.Command("cmd", "/c", "start", "", reportPath)
@@ -511,4 +1688,25 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Failed to open report: %v\n", err)
 		}
 	}
+
+	if uploadDest != "" {
+		candidates := []string{outPath, htmlPath, latexPath, badgePath, icsPath, covPath}
+		files := make([]string, 0, len(candidates))
+		for _, p := range candidates {
+			if p != "" {
+				files = append(files, p)
+			}
+		}
+		if outPath == "" {
+			fmt.Fprintln(os.Stderr, "CSV written to stdout: not uploaded (use -out)")
+		}
+		if err := uploadReports(uploadDest, files); err != nil {
+			fmt.Fprintf(os.Stderr, "Upload error: %v\n", err)
+			return 1
+		}
+	}
+	if len(charts.Alerts) > 0 {
+		return exitCode(ErrAlert)
+	}
+	return 0
 }
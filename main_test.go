@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// benchCloses returns n daily closes starting in 2000, keyed by month for monthlyReturns.
+func benchCloses(n int) map[time.Time]float64 {
+	points := make([]PricePoint, n)
+	d := time.Date(2000, 1, 3, 0, 0, 0, 0, time.UTC)
+	for i := range points {
+		points[i] = PricePoint{Date: d.AddDate(0, 0, i), Close: 100 + float64(i%97)}
+	}
+	return periodSeries(points, monthlyFrequency)
+}
+
+func BenchmarkMonthlyReturns(b *testing.B) {
+	closes := benchCloses(25 * 365)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		monthlyReturns(closes, nil)
+	}
+}
+
+func BenchmarkAlignReturns(b *testing.B) {
+	datesA, retsA := monthlyReturns(benchCloses(25*365), nil)
+	datesB, retsB := monthlyReturns(benchCloses(20*365), nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		alignReturns(datesA, retsA, datesB, retsB)
+	}
+}
+
+func BenchmarkWriteHTMLReport(b *testing.B) {
+	res := reportFixtures(b)[1].Res
+	charts := defaultChartOptions()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := writeHTMLReport(io.Discard, res, nil, chartJSCDN, charts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+)
+
+// Methodology captures the settings of a run that change how the report numbers are computed.
+type Methodology struct {
+	Interval      string
+	Freq          Frequency
+	Resampled     bool
+	Fill          string
+	Distributions bool
+	Excess        bool
+	RiskFree      string
+	CPI           string
+	LifeWeight    float64
+	Glide         []float64
+	Age           AgeGlide
+	Tent          BondTent
+	Overridden    int
+	Metrics       []Metric
+}
+
+// methodologyHTML renders the report appendix explaining how this run computed its numbers.
+func methodologyHTML(m Methodology) string {
+	w := &bytes.Buffer{}
+	p := func(format string, args ...any) {
+		_, _ = w.WriteString("<p>")
+		_, _ = fmt.Fprintf(w, format, args...)
+		_, _ = w.WriteString("</p>\n")
+	}
+	_, _ = w.WriteString("<h2>Methodology</h2>\n")
+	if m.Resampled {
+		p("Prices are Yahoo %s closes, bucketed into %s periods using the last close of each period.", html.EscapeString(m.Interval), m.Freq.Name)
+	} else {
+		p("Prices are Yahoo %s closes, used as %s periods without resampling.", html.EscapeString(m.Interval), m.Freq.Name)
+	}
+	if m.Fill != "none" {
+		p("Closes missing on one exchange were filled with the %s method before bucketing.", m.Fill)
+	}
+	ret := "Each period return is the change of the close from the previous period."
+	if m.Distributions {
+		ret += " Distributions paid during the period are added back to its return."
+	}
+	p("%s", ret)
+	p("Only periods present in both the ETF and the index series are compared. Alpha is the ETF period return minus the index period return; the average alpha is its mean over all compared periods and a period counts as a win when alpha is positive.")
+	if m.Excess {
+		p("All returns are in excess of the risk-free rate (%s).", html.EscapeString(m.RiskFree))
+	}
+	if m.CPI != "" {
+		p("Real returns divide each nominal return by the inflation of its month from %s.", html.EscapeString(m.CPI))
+	}
+	p("Cumulative tracks start at 100 and compound the period returns. LifeStrategy holds %.0f%% ETF and %.0f%% index, rebalanced every period.", m.LifeWeight*100, (1-m.LifeWeight)*100)
+	if m.Age.BirthYear != 0 {
+		p("GlidePath sets the ETF weight of each period to %.0f%% minus the investor's age (born %d), holding the age at %d after retirement and keeping the weight between 0%% and 100%%, rebalancing to the period's weight every period.", m.Age.Base, m.Age.BirthYear, m.Age.RetireAge)
+	} else if len(m.Glide) == 2 {
+		p("GlidePath moves the ETF weight linearly from %.0f%% in the first period to %.0f%% in the last (%s), rebalancing to the period's weight every period.", m.Glide[0]*100, m.Glide[1]*100, glideDirection(m.Glide))
+	} else {
+		p("GlidePath moves the ETF weight linearly through %s at evenly spaced points of the period (%s), rebalancing to the period's weight every period.", formatGlide(m.Glide), glideDirection(m.Glide))
+	}
+	if !m.Tent.Retire.IsZero() {
+		p("BondTent is drawn next to GlidePath: its ETF weight falls linearly from %.0f%% to %.0f%% over the %.0f years before %s and rises back to %.0f%% over the %.0f years after, rebalancing to the period's weight every period.", m.Glide[0]*100, (m.Glide[0]-m.Tent.Depth)*100, m.Tent.Years, m.Tent.Retire.Format("2006-01-02"), m.Glide[0]*100, m.Tent.Years)
+	}
+	if m.Overridden > 0 {
+		p("%d GlidePath periods use the ETF weight given for their month in the weights file instead.", m.Overridden)
+	}
+	if len(m.Metrics) > 0 {
+		_, _ = fmt.Fprintf(w, "<p>Metrics, annualized with %.0f periods per year where applicable:</p>\n<ul>\n", m.Freq.PeriodsPerYear)
+		for _, metric := range m.Metrics {
+			_, _ = fmt.Fprintf(w, "<li><b>%s</b>: %s</li>\n", html.EscapeString(metric.Title()), metricDescriptions[metric.Name()])
+		}
+		_, _ = w.WriteString("</ul>\n")
+	}
+	return w.String()
+}
+
+// metricDescriptions explains the built-in metrics in the methodology appendix.
+var metricDescriptions = map[string]string{
+	"avgalpha": "mean of ETF minus index period returns.",
+	"te":       "sample standard deviation of alpha, annualized.",
+	"ir":       "annualized mean alpha divided by tracking error.",
+	"sharpe":   "annualized mean return over the risk-free rate divided by its annualized standard deviation.",
+	"sortino":  "like Sharpe, but dividing by the root mean square of negative excess returns only.",
+	"capm":     "intercept of the regression of excess returns on the index's excess returns, annualized.",
+	"beta":     "slope of the regression of excess returns on the index's excess returns.",
+	"omega":    "sum of returns above the minimum acceptable return divided by the sum of shortfalls below it.",
+	"calmar":   "compound annual growth rate divided by the max drawdown.",
+	"downside": "root mean square shortfall below the minimum acceptable return, annualized.",
+	"ulcer":    "root mean square percentage drawdown from the running peak.",
+	"martin":   "compound annual growth rate over the risk-free rate divided by the Ulcer index.",
+	"maxdd":    "largest peak-to-trough fall of the compounded returns.",
+	"vol":      "sample standard deviation of period returns, annualized.",
+	"cagr":     "compound annual growth rate.",
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// MetricInput is the aligned data a metric is computed over: the periodic returns of the
+// series under study, those of its benchmark, the per-period risk-free rate and the
+// per-period minimum acceptable return.
+type MetricInput struct {
+	Returns        []float64
+	Benchmark      []float64
+	RiskFree       []float64
+	MAR            float64
+	PeriodsPerYear float64
+}
+
+// Metric is a summary statistic selectable with -metrics.
+type Metric interface {
+	Name() string
+	Title() string
+	Compute(in MetricInput) float64
+	Format(v float64) string
+}
+
+// MetricResult is a computed metric ready for display.
+type MetricResult struct {
+	Name  string
+	Title string
+	Value float64
+	Text  string
+}
+
+var metricRegistry = make(map[string]Metric)
+
+// RegisterMetric makes a metric selectable by name. Additional metrics register themselves
+// from an init function in their own file.
+func RegisterMetric(m Metric) {
+	metricRegistry[m.Name()] = m
+}
+
+// metricNames lists the registered metrics alphabetically.
+func metricNames() []string {
+	names := make([]string, 0, len(metricRegistry))
+	for n := range metricRegistry {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseMetrics resolves a comma separated list of metric names.
+func parseMetrics(value string) ([]Metric, error) {
+	out := make([]Metric, 0)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		m, ok := metricRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown metric %q (available: %s)", name, strings.Join(metricNames(), ","))
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func computeMetrics(metrics []Metric, in MetricInput) []MetricResult {
+	out := make([]MetricResult, 0, len(metrics))
+	for _, m := range metrics {
+		v := m.Compute(in)
+		out = append(out, MetricResult{Name: m.Name(), Title: m.Title(), Value: v, Text: m.Format(v)})
+	}
+	return out
+}
+
+func mean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return math.NaN()
+	}
+	s := 0.0
+	for _, x := range xs {
+		s += x
+	}
+	return s / float64(len(xs))
+}
+
+// stdDev is the sample standard deviation.
+func stdDev(xs []float64) float64 {
+	if len(xs) < 2 {
+		return math.NaN()
+	}
+	m := mean(xs)
+	ss := 0.0
+	for _, x := range xs {
+		ss += (x - m) * (x - m)
+	}
+	return math.Sqrt(ss / float64(len(xs)-1))
+}
+
+func activeReturns(in MetricInput) []float64 {
+	out := make([]float64, len(in.Returns))
+	for i := range in.Returns {
+		out[i] = in.Returns[i] - in.Benchmark[i]
+	}
+	return out
+}
+
+func excessReturns(in MetricInput) []float64 {
+	return excessOf(in.Returns, in.RiskFree)
+}
+
+func excessOf(returns []float64, riskFree []float64) []float64 {
+	out := make([]float64, len(returns))
+	for i := range returns {
+		out[i] = returns[i]
+		if riskFree != nil {
+			out[i] -= riskFree[i]
+		}
+	}
+	return out
+}
+
+// capm regresses the excess returns on the benchmark's excess returns.
+func capm(in MetricInput) (alpha float64, beta float64) {
+	x := excessOf(in.Benchmark, in.RiskFree)
+	y := excessReturns(in)
+	mx, my := mean(x), mean(y)
+	cov, v := 0.0, 0.0
+	for i := range x {
+		cov += (x[i] - mx) * (y[i] - my)
+		v += (x[i] - mx) * (x[i] - mx)
+	}
+	beta = cov / v
+	return my - beta*mx, beta
+}
+
+// maxDrawdown is the largest peak-to-trough fall of the compounded returns, as a positive fraction.
+func maxDrawdown(returns []float64) float64 {
+	v, peak, dd := 1.0, 1.0, 0.0
+	for _, r := range returns {
+		v *= 1 + r
+		if v > peak {
+			peak = v
+		}
+		if d := 1 - v/peak; d > dd {
+			dd = d
+		}
+	}
+	return dd
+}
+
+// downsideDeviation is the root mean square shortfall of returns below the MAR, annualized.
+func downsideDeviation(in MetricInput) float64 {
+	if len(in.Returns) == 0 {
+		return math.NaN()
+	}
+	ss := 0.0
+	for _, r := range in.Returns {
+		if d := r - in.MAR; d < 0 {
+			ss += d * d
+		}
+	}
+	return math.Sqrt(ss/float64(len(in.Returns))) * math.Sqrt(in.PeriodsPerYear)
+}
+
+// ulcerIndex is the root mean square percentage drawdown of the compounded returns.
+func ulcerIndex(returns []float64) float64 {
+	if len(returns) == 0 {
+		return math.NaN()
+	}
+	v, peak, ss := 1.0, 1.0, 0.0
+	for _, r := range returns {
+		v *= 1 + r
+		if v > peak {
+			peak = v
+		}
+		d := 100 * (1 - v/peak)
+		ss += d * d
+	}
+	return math.Sqrt(ss / float64(len(returns)))
+}
+
+// annualizedReturn compounds the returns into a yearly rate.
+func annualizedReturn(returns []float64, periodsPerYear float64) float64 {
+	if len(returns) == 0 {
+		return math.NaN()
+	}
+	v := 1.0
+	for _, r := range returns {
+		v *= 1 + r
+	}
+	return math.Pow(v, periodsPerYear/float64(len(returns))) - 1
+}
+
+// StrategyMetrics holds the selected metrics of one strategy measured against the index.
+type StrategyMetrics struct {
+	Name    string
+	Results []MetricResult
+}
+
+// strategyMetrics computes the selected metrics for every strategy of the report.
+func strategyMetrics(metrics []Metric, rows []ReportRow, riskFree []float64, mar float64, periodsPerYear float64) []StrategyMetrics {
+	returns := map[string][]float64{}
+	bench := make([]float64, len(rows))
+	for i, r := range rows {
+		returns["ETF"] = append(returns["ETF"], r.ETFReturn)
+		returns["Index"] = append(returns["Index"], r.IndexReturn)
+		returns["LifeStrategy"] = append(returns["LifeStrategy"], r.LifeReturn)
+		returns["GlidePath"] = append(returns["GlidePath"], r.GlideReturn)
+		bench[i] = r.IndexReturn
+	}
+	out := make([]StrategyMetrics, 0, len(chartSeries))
+	for _, cs := range chartSeries {
+		out = append(out, StrategyMetrics{Name: cs.Name, Results: computeMetrics(metrics, MetricInput{
+			Returns:        returns[cs.Name],
+			Benchmark:      bench,
+			RiskFree:       riskFree,
+			MAR:            mar,
+			PeriodsPerYear: periodsPerYear,
+		})})
+	}
+	return out
+}
+
+// funcMetric adapts plain functions to the Metric interface.
+type funcMetric struct {
+	name    string
+	title   string
+	compute func(in MetricInput) float64
+	format  func(v float64) string
+}
+
+func (m funcMetric) Name() string                   { return m.name }
+func (m funcMetric) Title() string                  { return m.title }
+func (m funcMetric) Compute(in MetricInput) float64 { return m.compute(in) }
+func (m funcMetric) Format(v float64) string        { return m.format(v) }
+
+// ratio divides num by den, returning NaN rather than an infinity when den is 0.
+func ratio(num float64, den float64) float64 {
+	if den == 0 {
+		return math.NaN()
+	}
+	return num / den
+}
+
+// formatMetric formats v with format, or shows n/a when the metric is undefined for the data.
+func formatMetric(format string, v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "n/a"
+	}
+	return fmt.Sprintf(format, v)
+}
+
+func formatRatio(v float64) string   { return formatMetric("%.2f", v) }
+func formatPercent(v float64) string { return formatMetric("%.2f%%", v*100) }
+func formatAlpha(v float64) string   { return formatMetric("%.5f", v) }
+
+func init() {
+	RegisterMetric(funcMetric{"avgalpha", "Avg alpha", func(in MetricInput) float64 {
+		return mean(activeReturns(in))
+	}, formatAlpha})
+	RegisterMetric(funcMetric{"te", "Tracking error", func(in MetricInput) float64 {
+		return stdDev(activeReturns(in)) * math.Sqrt(in.PeriodsPerYear)
+	}, formatPercent})
+	RegisterMetric(funcMetric{"ir", "Information ratio", func(in MetricInput) float64 {
+		active := activeReturns(in)
+		return mean(active) / stdDev(active) * math.Sqrt(in.PeriodsPerYear)
+	}, formatRatio})
+	RegisterMetric(funcMetric{"sharpe", "Sharpe ratio", func(in MetricInput) float64 {
+		excess := excessReturns(in)
+		return mean(excess) / stdDev(excess) * math.Sqrt(in.PeriodsPerYear)
+	}, formatRatio})
+	RegisterMetric(funcMetric{"sortino", "Sortino ratio", func(in MetricInput) float64 {
+		excess := excessReturns(in)
+		downside := 0.0
+		for _, x := range excess {
+			if x < 0 {
+				downside += x * x
+			}
+		}
+		return ratio(mean(excess), math.Sqrt(downside/float64(len(excess)))) * math.Sqrt(in.PeriodsPerYear)
+	}, formatRatio})
+	RegisterMetric(funcMetric{"capm", "CAPM alpha (annualized)", func(in MetricInput) float64 {
+		alpha, _ := capm(in)
+		return alpha * in.PeriodsPerYear
+	}, formatPercent})
+	RegisterMetric(funcMetric{"beta", "Beta", func(in MetricInput) float64 {
+		_, beta := capm(in)
+		return beta
+	}, formatRatio})
+	RegisterMetric(funcMetric{"maxdd", "Max drawdown", func(in MetricInput) float64 {
+		return maxDrawdown(in.Returns)
+	}, formatPercent})
+	RegisterMetric(funcMetric{"vol", "Volatility", func(in MetricInput) float64 {
+		return stdDev(in.Returns) * math.Sqrt(in.PeriodsPerYear)
+	}, formatPercent})
+	RegisterMetric(funcMetric{"cagr", "CAGR", func(in MetricInput) float64 {
+		return annualizedReturn(in.Returns, in.PeriodsPerYear)
+	}, formatPercent})
+	RegisterMetric(funcMetric{"omega", "Omega ratio", func(in MetricInput) float64 {
+		gains, losses := 0.0, 0.0
+		for _, r := range in.Returns {
+			if d := r - in.MAR; d > 0 {
+				gains += d
+			} else {
+				losses -= d
+			}
+		}
+		return ratio(gains, losses)
+	}, formatRatio})
+	RegisterMetric(funcMetric{"calmar", "Calmar ratio", func(in MetricInput) float64 {
+		return ratio(annualizedReturn(in.Returns, in.PeriodsPerYear), maxDrawdown(in.Returns))
+	}, formatRatio})
+	RegisterMetric(funcMetric{"downside", "Downside deviation", downsideDeviation, formatPercent})
+	RegisterMetric(funcMetric{"ulcer", "Ulcer index", func(in MetricInput) float64 {
+		return ulcerIndex(in.Returns)
+	}, formatRatio})
+	RegisterMetric(funcMetric{"martin", "Martin ratio", func(in MetricInput) float64 {
+		rf := 0.0
+		if in.RiskFree != nil {
+			rf = annualizedReturn(in.RiskFree, in.PeriodsPerYear)
+		}
+		return ratio((annualizedReturn(in.Returns, in.PeriodsPerYear)-rf)*100, ulcerIndex(in.Returns))
+	}, formatRatio})
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMetricsWithoutLosses(t *testing.T) {
+	// Returns that never fall have no downside, shortfall or drawdown to divide by.
+	in := MetricInput{
+		Returns:        []float64{0.01, 0.02, 0.015},
+		Benchmark:      []float64{0.01, 0.01, 0.01},
+		PeriodsPerYear: 12,
+	}
+	for _, name := range []string{"sortino", "omega", "calmar", "martin"} {
+		m := metricRegistry[name]
+		v := m.Compute(in)
+		if !math.IsNaN(v) {
+			t.Errorf("%s = %v, want NaN", name, v)
+		}
+		if got := m.Format(v); got != "n/a" {
+			t.Errorf("%s shows %q, want n/a", name, got)
+		}
+	}
+	if got := formatPercent(0.0123); got != "1.23%" {
+		t.Errorf("formatPercent(0.0123) = %q", got)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// OptConstraints bound the weights of the mean-variance optimizer. Weights always sum to one.
+type OptConstraints struct {
+	NoShort   bool
+	MaxWeight float64 // upper bound of every weight
+}
+
+// bounds returns the lower and upper limit of every weight.
+func (c OptConstraints) bounds() (float64, float64) {
+	lo := math.Inf(-1)
+	if c.NoShort {
+		lo = 0
+	}
+	return lo, c.MaxWeight
+}
+
+// validate checks that n assets can be fully invested under the constraints.
+func (c OptConstraints) validate(n int) error {
+	if c.MaxWeight <= 0 {
+		return fmt.Errorf("opt-max-weight must be positive")
+	}
+	if c.MaxWeight*float64(n) < 1 {
+		return fmt.Errorf("opt-max-weight %.2f cannot fully invest %d assets", c.MaxWeight, n)
+	}
+	return nil
+}
+
+// projectBounded returns the Euclidean projection of v onto {w : lo <= w <= hi, sum(w) = 1},
+// bisecting on the shift that makes the clamped weights sum to one.
+func projectBounded(v []float64, lo float64, hi float64) []float64 {
+	clamped := func(theta float64) float64 {
+		sum := 0.0
+		for _, x := range v {
+			sum += math.Max(lo, math.Min(hi, x-theta))
+		}
+		return sum
+	}
+	a, b := math.Inf(1), math.Inf(-1)
+	for _, x := range v {
+		a, b = math.Min(a, x), math.Max(b, x)
+	}
+	a, b = a-1, b+1
+	for iter := 0; iter < 100; iter++ {
+		mid := (a + b) / 2
+		if clamped(mid) > 1 {
+			a = mid
+		} else {
+			b = mid
+		}
+	}
+	theta := (a + b) / 2
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = math.Max(lo, math.Min(hi, x-theta))
+	}
+	return out
+}
+
+// meanVarianceWeights minimizes w'Σw/2 − λ w'μ over the constrained weights by projected
+// gradient descent, starting from equal weights.
+func meanVarianceWeights(mu []float64, cov [][]float64, lambda float64, c OptConstraints) []float64 {
+	n := len(mu)
+	lo, hi := c.bounds()
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 1 / float64(n)
+	}
+	// The trace of Σ bounds its largest eigenvalue, which keeps the step stable.
+	trace := 0.0
+	for i := range cov {
+		trace += cov[i][i]
+	}
+	if trace == 0 {
+		return w
+	}
+	step := 1 / trace
+	next := make([]float64, n)
+	for iter := 0; iter < 20000; iter++ {
+		for i := range w {
+			grad := -lambda * mu[i]
+			for j := range w {
+				grad += cov[i][j] * w[j]
+			}
+			next[i] = w[i] - step*grad
+		}
+		proj := projectBounded(next, lo, hi)
+		moved := 0.0
+		for i := range w {
+			moved += math.Abs(proj[i] - w[i])
+		}
+		w = proj
+		if moved < 1e-12 {
+			break
+		}
+	}
+	return w
+}
+
+// OptTarget selects the frontier portfolio: the least risky one expecting at least the annual
+// Return, or the best one within the annual volatility Risk, when set, and the minimum
+// variance portfolio otherwise.
+type OptTarget struct {
+	Return float64
+	Risk   float64
+}
+
+// OptimalPortfolio is the mean-variance optimum over the history of the assets.
+type OptimalPortfolio struct {
+	Name    string
+	Symbols []string
+	Weights []float64
+	Return  float64 // annual expected (arithmetic) return
+	Risk    float64 // annual volatility
+	Reached bool    // false when the target lies beyond the feasible frontier
+}
+
+// assetMoments estimates the per-period mean and covariance of the aligned asset returns.
+func assetMoments(cols [][]float64) ([]float64, [][]float64) {
+	mu := make([]float64, len(cols))
+	for j, col := range cols {
+		for _, v := range col {
+			mu[j] += v
+		}
+		mu[j] /= float64(len(col))
+	}
+	return mu, covarianceMatrix(cols)
+}
+
+// frontierPortfolio annualizes the expected return and risk of the weights.
+func frontierPortfolio(name string, symbols []string, w []float64, mu []float64, cov [][]float64, periodsPerYear float64) OptimalPortfolio {
+	p := OptimalPortfolio{Name: name, Symbols: symbols, Weights: w, Reached: true}
+	variance := 0.0
+	for i := range w {
+		p.Return += w[i] * mu[i] * periodsPerYear
+		for j := range w {
+			variance += w[i] * cov[i][j] * w[j]
+		}
+	}
+	p.Risk = math.Sqrt(math.Max(variance, 0) * periodsPerYear)
+	return p
+}
+
+// optimizePortfolio walks the frontier of the per-period estimates mu and cov by bisecting on
+// the risk aversion until the target return or risk is met.
+func optimizePortfolio(name string, symbols []string, mu []float64, cov [][]float64, target OptTarget, c OptConstraints, periodsPerYear float64) OptimalPortfolio {
+	solve := func(lambda float64) OptimalPortfolio {
+		return frontierPortfolio(name, symbols, meanVarianceWeights(mu, cov, lambda, c), mu, cov, periodsPerYear)
+	}
+	// Along the frontier both the return and the risk grow with lambda.
+	above := func(p OptimalPortfolio) bool {
+		if target.Return != 0 {
+			return p.Return >= target.Return
+		}
+		return p.Risk >= target.Risk
+	}
+	minVar := solve(0)
+	switch {
+	case target.Return == 0 && target.Risk == 0:
+		return minVar
+	case above(minVar):
+		// Even the least risky portfolio earns the target return or exceeds the target risk.
+		minVar.Reached = target.Return != 0
+		return minVar
+	}
+	lambdaHi := 1e-3
+	for !above(solve(lambdaHi)) {
+		if lambdaHi > 1e6 {
+			p := solve(lambdaHi)
+			p.Reached = false
+			return p
+		}
+		lambdaHi *= 4
+	}
+	lambdaLo := 0.0
+	for iter := 0; iter < 50; iter++ {
+		mid := (lambdaLo + lambdaHi) / 2
+		if above(solve(mid)) {
+			lambdaHi = mid
+		} else {
+			lambdaLo = mid
+		}
+	}
+	return solve(lambdaHi)
+}
+
+// cappedOptimum walks the frontier of mu and cov from the minimum variance portfolio towards
+// ever higher risk aversion settings and keeps the portfolio with the best realized CAGR
+// whose historical max drawdown on cols stayed within ceiling. ok is false when none did.
+func cappedOptimum(name string, symbols []string, mu []float64, cov [][]float64, cols [][]float64, c OptConstraints, ceiling float64, periodsPerYear float64) (OptimalPortfolio, bool) {
+	best, bestCAGR, ok := OptimalPortfolio{}, math.Inf(-1), false
+	for lambda := 0.0; lambda <= 1e6; lambda = math.Max(lambda*1.25, 1e-3) {
+		w := meanVarianceWeights(mu, cov, lambda, c)
+		returns := portfolioReturns(cols, w)
+		if maxDrawdown(returns) > ceiling {
+			continue
+		}
+		if cagr := annualizedReturn(returns, periodsPerYear); cagr > bestCAGR {
+			best = frontierPortfolio(name, symbols, w, mu, cov, periodsPerYear)
+			bestCAGR, ok = cagr, true
+		}
+	}
+	return best, ok
+}
+
+// portfolioReturns rebalances the assets to the weights every period.
+func portfolioReturns(cols [][]float64, weights []float64) []float64 {
+	out := make([]float64, len(cols[0]))
+	for j, col := range cols {
+		for i, v := range col {
+			out[i] += weights[j] * v
+		}
+	}
+	return out
+}
+
+// TrackReturns is a named series of per-period returns.
+type TrackReturns struct {
+	Name    string
+	Returns []float64
+}
+
+// writeOptimizerReport prints the weights and expected return and risk of each optimal
+// portfolio, then the realized track of each next to the others over the same periods.
+func writeOptimizerReport(w io.Writer, opts []OptimalPortfolio, cols [][]float64, others []TrackReturns, periodsPerYear float64) {
+	tracks := make([]TrackReturns, 0, len(opts)+len(others))
+	for _, opt := range opts {
+		fmt.Fprintf(w, "Optimizer %s: %s, expected return %.2f%%, volatility %.2f%% per year", opt.Name, formatWeights(opt.Symbols, opt.Weights), opt.Return*100, opt.Risk*100)
+		if !opt.Reached {
+			fmt.Fprint(w, " (target not reachable under the constraints)")
+		}
+		fmt.Fprintln(w)
+		tracks = append(tracks, TrackReturns{opt.Name, portfolioReturns(cols, opt.Weights)})
+	}
+	tracks = append(tracks, others...)
+	fmt.Fprintf(w, "%-14s %9s %9s %9s\n", "Portfolio", "CAGR", "Vol", "Max DD")
+	for _, t := range tracks {
+		in := MetricInput{Returns: t.Returns, PeriodsPerYear: periodsPerYear}
+		fmt.Fprintf(w, "%-14s %8.2f%% %8.2f%% %8.2f%%\n", t.Name, metricRegistry["cagr"].Compute(in)*100, metricRegistry["vol"].Compute(in)*100, maxDrawdown(t.Returns)*100)
+	}
+}
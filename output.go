@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic replaces path with data through a temporary file in the same directory
+// and a rename, so readers never see a partial file. When the file already holds exactly
+// data it is left untouched (keeping its mtime) and changed is false.
+func writeFileAtomic(path string, data []byte) (changed bool, err error) {
+	if old, err := outputFS.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		return false, nil
+	}
+
+	tmp, err := outputFS.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return false, fmt.Errorf("create temp for %s: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = outputFS.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return false, fmt.Errorf("write %s: %w", path, err)
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return false, fmt.Errorf("sync %s: %w", path, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return false, fmt.Errorf("close %s: %w", path, err)
+	}
+	if err = outputFS.Chmod(tmp.Name(), 0o644); err != nil {
+		return false, fmt.Errorf("chmod %s: %w", path, err)
+	}
+	if err = outputFS.Rename(tmp.Name(), path); err != nil {
+		return false, fmt.Errorf("rename %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// writeCSVOutput writes the CSV to stdout or, atomically, to path (compressed when the
+// extension asks for it). With appendMode only rows newer than the last row of the
+// existing file are added, their cumulative tracks continuing from that row.
+func writeCSVOutput(path string, appendMode bool, csvData []byte, rows []ReportRow, columns []csvColumn, dateFormat string) error {
+	if path == "" {
+		if appendMode {
+			return errors.New("-append needs -out")
+		}
+		_, err := os.Stdout.Write(csvData)
+		return err
+	}
+
+	data := csvData
+	if appendMode {
+		if isCompressedPath(path) {
+			return errors.New("-append does not support compressed outputs")
+		}
+		existing, err := outputFS.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if len(existing) > 0 {
+			tail, err := parseCSVTail(existing)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			from, err := appendStart(tail, rows, columns, dateFormat)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			appended := rows[from:]
+			if from > 0 {
+				if appended, err = rebaseRows(tail, rows[from-1], appended); err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+			}
+			var buf bytes.Buffer
+			buf.Write(existing)
+			writeCSV(&buf, appended, columns, dateFormat, false)
+			data = buf.Bytes()
+			fmt.Fprintf(os.Stderr, "Appending %d new rows to %s\n", len(rows)-from, path)
+		}
+	}
+
+	if isCompressedPath(path) {
+		var buf bytes.Buffer
+		cw, err := compressedWriter(path, &buf)
+		if err != nil {
+			return err
+		}
+		if _, err := cw.Write(data); err != nil {
+			return err
+		}
+		if err := cw.Close(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+
+	changed, err := writeFileAtomic(path, data)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Fprintf(os.Stderr, "%s unchanged\n", path)
+	}
+	return nil
+}
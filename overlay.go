@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Window is a closed date range of the analysis.
+type Window struct {
+	From time.Time
+	To   time.Time
+}
+
+// parseWindows parses comma separated FROM:TO date ranges (YYYY-MM-DD).
+func parseWindows(value string) ([]Window, error) {
+	out := make([]Window, 0)
+	for _, part := range strings.Split(value, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid window %q (want FROM:TO)", part)
+		}
+		var w Window
+		var err error
+		if w.From, err = time.Parse("2006-01-02", from); err != nil {
+			return nil, fmt.Errorf("invalid window start %q", from)
+		}
+		if w.To, err = time.Parse("2006-01-02", to); err != nil {
+			return nil, fmt.Errorf("invalid window end %q", to)
+		}
+		if !w.From.Before(w.To) {
+			return nil, fmt.Errorf("window %q ends before it starts", part)
+		}
+		out = append(out, w)
+	}
+	if len(out) < 2 {
+		return nil, fmt.Errorf("overlay needs at least two windows")
+	}
+	return out, nil
+}
+
+// OverlayLine is one window of the overlay chart, indexed by period within the window.
+type OverlayLine struct {
+	Label  string
+	Values []float64
+}
+
+// overlayLines runs one strategy over each window and normalizes it to 100 at the window
+// start. returnsFor returns the strategy's returns for the periods [start, start+n).
+func overlayLines(dates []time.Time, windows []Window, returnsFor func(start int, n int) []float64, freq Frequency) []OverlayLine {
+	out := make([]OverlayLine, 0, len(windows))
+	for _, w := range windows {
+		lo := sort.Search(len(dates), func(i int) bool { return !dates[i].Before(freq.Key(w.From)) })
+		hi := sort.Search(len(dates), func(i int) bool { return dates[i].After(w.To) })
+		if hi <= lo {
+			continue
+		}
+		out = append(out, OverlayLine{
+			Label:  freq.Label(dates[lo]) + " – " + freq.Label(dates[hi-1]),
+			Values: cumulative(100, returnsFor(lo, hi-lo)),
+		})
+	}
+	return out
+}
+
+// overlayJS draws the overlay lines against the period number on the overlayChart canvas.
+func overlayJS(lines []OverlayLine, strategy string, freq Frequency) string {
+	longest := 0
+	for _, l := range lines {
+		longest = max(longest, len(l.Values))
+	}
+	var b strings.Builder
+	b.WriteString("new Chart(document.getElementById('overlayChart'),{type:'line',data:{labels:[")
+	for i := 1; i <= longest; i++ {
+		if i > 1 {
+			b.WriteString(",")
+		}
+		_, _ = fmt.Fprintf(&b, "%d", i)
+	}
+	b.WriteString("],datasets:[")
+	for li, l := range lines {
+		if li > 0 {
+			b.WriteString(",")
+		}
+		color := linePalette[li%len(linePalette)]
+		_, _ = fmt.Fprintf(&b, "{label:%q,data:[", html.EscapeString(l.Label))
+		for i, v := range l.Values {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			_, _ = fmt.Fprintf(&b, "%.2f", v)
+		}
+		_, _ = fmt.Fprintf(&b, "],borderColor:'%s',backgroundColor:'%s',pointRadius:0,tension:0.2}", color, fillColor(color, 0.1))
+	}
+	_, _ = fmt.Fprintf(&b, "]},options:{plugins:{legend:{position:'bottom'}},scales:{x:{title:{display:true,text:'%s periods from window start'}},y:{title:{display:true,text:'%s (base 100)'}}}}});\n",
+		freq.Title, strategy)
+	return b.String()
+}
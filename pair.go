@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// PairedETF compares a second ETF, e.g. another share class of the same fund, with the main
+// one on the same benchmark. Values are keyed by period label.
+type PairedETF struct {
+	Symbol     string
+	Alpha      map[string]float64 // second ETF return minus index return
+	HeadToHead map[string]float64 // main ETF relative to the second one, base 100
+	Final      float64            // last head-to-head value
+	AvgAlpha   float64
+	AvgDiff    float64 // average main minus second ETF return
+	Wins       int     // periods the main ETF beat the second one
+	Periods    int
+}
+
+// pairedETF aligns the second ETF's returns with the report rows; rows without a return for
+// it are left out of every series.
+func pairedETF(symbol string, rows []ReportRow, dates []time.Time, rets []float64) PairedETF {
+	byDate := make(map[time.Time]float64, len(dates))
+	for i, d := range dates {
+		byDate[d] = rets[i]
+	}
+	pair := PairedETF{Symbol: symbol, Alpha: make(map[string]float64), HeadToHead: make(map[string]float64)}
+	head := 100.0
+	for _, r := range rows {
+		r2, ok := byDate[r.Period]
+		if !ok {
+			continue
+		}
+		alpha := r2 - r.IndexReturn
+		pair.Alpha[r.Date] = alpha
+		head *= (1 + r.ETFReturn) / (1 + r2)
+		pair.HeadToHead[r.Date] = head
+		pair.AvgAlpha += alpha
+		pair.AvgDiff += r.ETFReturn - r2
+		if r.ETFReturn > r2 {
+			pair.Wins++
+		}
+		pair.Periods++
+	}
+	pair.Final = head
+	if pair.Periods > 0 {
+		pair.AvgAlpha /= float64(pair.Periods)
+		pair.AvgDiff /= float64(pair.Periods)
+	}
+	return pair
+}
+
+// pairHTML lays out the paired alpha and head-to-head charts of the two ETFs.
+func pairHTML(etf string, pair PairedETF, height int) string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "<h2>%s vs %s</h2>\n", html.EscapeString(etf), html.EscapeString(pair.Symbol))
+	_, _ = fmt.Fprintf(&b, "<p>%s beat %s in %d of %d periods, by %.5f on average; avg alpha %.5f.</p>\n",
+		html.EscapeString(etf), html.EscapeString(pair.Symbol), pair.Wins, pair.Periods, pair.AvgDiff, pair.AvgAlpha)
+	_, _ = fmt.Fprintf(&b, "<canvas id=\"pairAlphaChart\" height=\"%d\"></canvas>\n", height)
+	_, _ = fmt.Fprintf(&b, "<canvas id=\"headChart\" height=\"%d\"></canvas>\n", height)
+	return b.String()
+}
+
+// pairJS draws both ETFs' alpha side by side on the report labels and the head-to-head
+// series below it. It expects labels and alphaData to be defined.
+func pairJS(etf string, pair PairedETF, rows []ReportRow, alphaColor string, style string, freq Frequency) string {
+	var b strings.Builder
+	series := func(name string, values map[string]float64, format string) {
+		_, _ = fmt.Fprintf(&b, "const %s = [", name)
+		for i, r := range rows {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			if v, ok := values[r.Date]; ok {
+				_, _ = fmt.Fprintf(&b, format, v)
+			} else {
+				b.WriteString("null")
+			}
+		}
+		b.WriteString("];\n")
+	}
+	series("pairAlphaData", pair.Alpha, "%.5f")
+	series("headData", pair.HeadToHead, "%.2f")
+	color := linePalette[0]
+	_, _ = fmt.Fprintf(&b, "new Chart(document.getElementById('pairAlphaChart'),{type:'%s',data:{labels:labels,datasets:[", style)
+	_, _ = fmt.Fprintf(&b, "{label:%q,data:alphaData,backgroundColor:'%s',borderColor:'%s'},", html.EscapeString(etf), fillColor(alphaColor, 0.35), alphaColor)
+	_, _ = fmt.Fprintf(&b, "{label:%q,data:pairAlphaData,backgroundColor:'%s',borderColor:'%s',spanGaps:true}]},", html.EscapeString(pair.Symbol), fillColor(color, 0.35), color)
+	_, _ = fmt.Fprintf(&b, "options:{plugins:{legend:{position:'bottom'}},scales:{y:{title:{display:true,text:'%s alpha'}}}}});\n", freq.Title)
+	_, _ = fmt.Fprintf(&b, "new Chart(document.getElementById('headChart'),{type:'line',data:{labels:labels,datasets:[{label:%q,data:headData,borderColor:'%s',backgroundColor:'%s',tension:0.2,spanGaps:true}]},",
+		html.EscapeString(etf+" / "+pair.Symbol), color, fillColor(color, 0.1))
+	b.WriteString("options:{plugins:{legend:{position:'bottom'}},scales:{y:{title:{display:true,text:'Head-to-head (base 100)'}}}}});\n")
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// ChartLine is an extra series drawn on the cumulative chart, keyed by row date.
+type ChartLine struct {
+	Label  string
+	Values map[string]float64
+}
+
+type recommendationsResponse struct {
+	Finance struct {
+		Result []struct {
+			Symbol             string `json:"symbol"`
+			RecommendedSymbols []struct {
+				Symbol string  `json:"symbol"`
+				Score  float64 `json:"score"`
+			} `json:"recommendedSymbols"`
+		} `json:"result"`
+	} `json:"finance"`
+}
+
+// fetchPeers asks Yahoo for the symbols it considers similar to symbol, best score first.
+func fetchPeers(symbol string, limit int) ([]string, error) {
+	var data recommendationsResponse
+	if err := getYahooJSON("/v6/finance/recommendationsbysymbol/"+url.PathEscape(symbol), nil, &data); err != nil {
+		return nil, fmt.Errorf("peers %s: %w", symbol, err)
+	}
+
+	peers := make([]string, 0, limit)
+	for _, r := range data.Finance.Result {
+		for _, rec := range r.RecommendedSymbols {
+			if len(peers) == limit {
+				return peers, nil
+			}
+			if rec.Symbol != symbol {
+				peers = append(peers, rec.Symbol)
+			}
+		}
+	}
+	return peers, nil
+}
+
+// peerLine compounds a peer's periodic returns over the comparison dates. Periods the peer
+// has no data for are left out of the returned map and show as gaps on the chart.
+func peerLine(symbol string, dates []time.Time, peerDates []time.Time, peerRets []float64, freq Frequency) ChartLine {
+	index := make(map[time.Time]float64, len(peerDates))
+	for i, d := range peerDates {
+		index[d] = peerRets[i]
+	}
+	line := ChartLine{Label: symbol, Values: make(map[string]float64, len(dates))}
+	v := 100.0
+	for _, d := range dates {
+		r, ok := index[d]
+		if !ok {
+			continue
+		}
+		v *= 1 + r
+		line.Values[freq.Label(d)] = v
+	}
+	return line
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startProfiling starts a CPU profile when cpuPath is set. The returned function stops it
+// and writes a heap profile to memPath when set; call it before the program exits.
+func startProfiling(cpuPath string, memPath string) (func(), error) {
+	var cpu io.WriteCloser
+	if cpuPath != "" {
+		f, err := outputFS.Create(cpuPath)
+		if err != nil {
+			return nil, fmt.Errorf("create cpu profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("start cpu profile: %w", err)
+		}
+		cpu = f
+	}
+
+	return func() {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			_ = cpu.Close()
+		}
+		if memPath != "" {
+			f, err := outputFS.Create(memPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Create memory profile: %v\n", err)
+				return
+			}
+			defer func() {
+				_ = f.Close()
+			}()
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				fmt.Fprintf(os.Stderr, "Write memory profile: %v\n", err)
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	yahoofinanceapi "github.com/oscarli916/yahoo-finance-api"
+)
+
+// PriceProvider downloads the closing prices of a symbol between start and end, both dates
+// included; a zero end means the latest data. Unknown symbols return an error wrapping
+// ErrSymbolNotFound.
+type PriceProvider interface {
+	Fetch(symbol string, start time.Time, end time.Time, interval string) (Series, error)
+}
+
+// providers are the price sources selectable with -provider.
+var providers = map[string]PriceProvider{
+	"yahoo":        yahooProvider{},
+	"alphavantage": alphaVantageProvider{},
+}
+
+func providerNames() string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// selectProvider resolves a provider name, giving Alpha Vantage its API key.
+func selectProvider(name string, avKey string) (PriceProvider, error) {
+	selected, ok := providers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q (want %s)", name, providerNames())
+	}
+	if av, ok := selected.(alphaVantageProvider); ok {
+		if avKey == "" {
+			return nil, fmt.Errorf("provider alphavantage needs -alphavantage-key or ALPHAVANTAGE_API_KEY")
+		}
+		av.Key = avKey
+		selected = av
+	}
+	return selected, nil
+}
+
+// priceProvider is the source loadPrices downloads from.
+var priceProvider PriceProvider = yahooProvider{}
+
+// fallbackProvider, when set, answers in place of priceProvider while its circuit is open.
+var fallbackProvider PriceProvider
+
+// PriceQuery is the range and bar size every series of a run is loaded with.
+type PriceQuery struct {
+	Start    time.Time
+	End      time.Time // last day included, zero for the latest data
+	Interval string
+}
+
+// loadPrices returns the history for the query from the cache when it is fresh, fetches only
+// the bars after a stale cached series, and downloads it in full otherwise.
+func loadPrices(symbol string, query PriceQuery) (Series, error) {
+	if historyCache == nil {
+		series, err := fetchPrices(symbol, query)
+		if err != nil {
+			return fetchFallback(symbol, query, err)
+		}
+		return series, nil
+	}
+	// An empty entry is fetched again in full: the symbol may have gained data since.
+	entry, ok := historyCache.Lookup(symbol, query)
+	if !ok || len(entry.Points) == 0 {
+		series, err := fetchPrices(symbol, query)
+		if err != nil {
+			return fetchFallback(symbol, query, err)
+		}
+		historyCache.Store(query, series)
+		return series, nil
+	}
+	if since(entry.Fetched) <= historyCache.TTL {
+		return entry.Series(query.Start), nil
+	}
+	// The last cached bar is fetched again in case it was still forming.
+	update := query
+	update.Start = entry.Points[len(entry.Points)-1].Date
+	fresh, err := fetchPrices(symbol, update)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: using cached %s history: %v\n", symbol, err)
+		return entry.Series(query.Start), nil
+	}
+	entry.Points = mergePoints(entry.Points, fresh.Points)
+	cached := query
+	cached.Start, _ = time.Parse("2006-01-02", entry.Start)
+	historyCache.Store(cached, Series{Symbol: symbol, Points: entry.Points})
+	return entry.Series(query.Start), nil
+}
+
+// fetchPrices calls the provider through the rate limiter and circuit breaker.
+func fetchPrices(symbol string, query PriceQuery) (Series, error) {
+	if err := providerBreaker.Allow(); err != nil {
+		return Series{}, fmt.Errorf("history error %s: %w", symbol, err)
+	}
+	providerLimiter.Wait()
+	series, err := priceProvider.Fetch(symbol, query.Start, query.End, query.Interval)
+	// An unknown symbol is a valid answer, not a provider failure.
+	if errors.Is(err, ErrSymbolNotFound) {
+		providerBreaker.Record(nil)
+	} else {
+		providerBreaker.Record(err)
+	}
+	return series, err
+}
+
+// fetchFallback downloads from the fallback provider when err is the open circuit of the main
+// one, and returns err otherwise. Fallback bars are not cached: they may be adjusted
+// differently from the main provider's.
+func fetchFallback(symbol string, query PriceQuery, err error) (Series, error) {
+	if fallbackProvider == nil || !errors.Is(err, errCircuitOpen) {
+		return Series{}, err
+	}
+	fmt.Fprintf(os.Stderr, "Ticker %s: circuit open, fetching from the fallback provider\n", symbol)
+	providerLimiter.Wait()
+	return fallbackProvider.Fetch(symbol, query.Start, query.End, query.Interval)
+}
+
+// yahooProvider reads the Yahoo chart endpoint through the yahoo-finance-api library.
+type yahooProvider struct{}
+
+func (yahooProvider) Fetch(symbol string, start time.Time, end time.Time, interval string) (Series, error) {
+	query := yahoofinanceapi.HistoryQuery{
+		Start:    start.Format("2006-01-02"),
+		Interval: interval,
+	}
+	// Yahoo's end bound is exclusive, so the query runs to the start of the following day.
+	if !end.IsZero() {
+		query.End = fmt.Sprintf("%d", end.AddDate(0, 0, 1).Unix())
+	}
+	data, err := yahooTicker(symbol).History(query)
+	if err != nil && strings.Contains(err.Error(), "no data found") {
+		return Series{}, fmt.Errorf("history error %s: %w", symbol, ErrSymbolNotFound)
+	}
+	if err != nil {
+		return Series{}, fmt.Errorf("history error %s: %w", symbol, err)
+	}
+
+	points := make([]PricePoint, 0, len(data))
+	skipped := 0
+	for dateStr, price := range data {
+		if math.IsNaN(price.Close) || price.Close <= 0 {
+			skipped++
+			continue
+		}
+
+		d, err := parsePriceDate(dateStr)
+		if err != nil {
+			return Series{}, fmt.Errorf("parse date for %s: %w", symbol, err)
+		}
+
+		points = append(points, PricePoint{
+			Date:  d,
+			Close: price.Close,
+		})
+	}
+
+	sort.Slice(points, func(i, j int) bool { return points[i].Date.Before(points[j].Date) })
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Ticker %s: skipped %d NaN Close points\n", symbol, skipped)
+	}
+
+	return Series{Symbol: symbol, Points: points}, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket is a rate limiter allowing rate requests per second on average with bursts
+// of up to burst requests.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: clock.Now()}
+}
+
+// Wait blocks until a token is available. A nil bucket or a non-positive rate never blocks.
+func (b *TokenBucket) Wait() {
+	if b == nil || b.rate <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := clock.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		time.Sleep(wait)
+		b.last = clock.Now()
+		b.tokens = 0
+		return
+	}
+	b.tokens--
+}
+
+// providerLimiter throttles every call made to the price provider during the run.
+var providerLimiter *TokenBucket
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// RebalanceStats compares a blend rebalanced every period with buy-and-hold from the same
+// initial weights.
+type RebalanceStats struct {
+	RebalancedCAGR float64
+	BuyHoldCAGR    float64
+	Turnover       float64 // one-way, annualized
+	Rebalances     int
+}
+
+func annualize(final float64, periods int, periodsPerYear float64) float64 {
+	return math.Pow(final, periodsPerYear/float64(periods)) - 1
+}
+
+// rebalanceStats measures the rebalancing premium of an ETF weight w over the aligned returns.
+func rebalanceStats(retsA []float64, retsB []float64, w float64, periodsPerYear float64) RebalanceStats {
+	var st RebalanceStats
+	if len(retsA) == 0 {
+		return st
+	}
+	rebalanced := 1.0
+	holdA, holdB := w, 1-w
+	traded := 0.0
+	for i := range retsA {
+		growA, growB := w*(1+retsA[i]), (1-w)*(1+retsB[i])
+		rebalanced *= growA + growB
+		holdA *= 1 + retsA[i]
+		holdB *= 1 + retsB[i]
+		if i == len(retsA)-1 {
+			break
+		}
+		if drift := math.Abs(growA/(growA+growB) - w); drift > 1e-9 {
+			traded += drift
+			st.Rebalances++
+		}
+	}
+	st.RebalancedCAGR = annualize(rebalanced, len(retsA), periodsPerYear)
+	st.BuyHoldCAGR = annualize(holdA+holdB, len(retsA), periodsPerYear)
+	st.Turnover = traded / (float64(len(retsA)) / periodsPerYear)
+	return st
+}
+
+func writeRebalanceReport(w io.Writer, st RebalanceStats, weight float64, freq Frequency) {
+	fmt.Fprintf(w, "Rebalancing premium at ETF weight %.2f: rebalanced CAGR %.2f%%, buy-and-hold CAGR %.2f%%, difference %.2f%% per year\n",
+		weight, st.RebalancedCAGR*100, st.BuyHoldCAGR*100, (st.RebalancedCAGR-st.BuyHoldCAGR)*100)
+	fmt.Fprintf(w, "Rebalancing: %d %s rebalances, turnover %.2f%% per year\n", st.Rebalances, freq.Name, st.Turnover*100)
+}
+
+// glideTurnover returns the amount traded at the end of each period to move the glide path
+// from its drifted ETF weight to the next period's target, for a portfolio starting at 100.
+func glideTurnover(retsA []float64, retsB []float64, weights []float64) []float64 {
+	traded := make([]float64, len(retsA))
+	v := 100.0
+	for i := range retsA {
+		growA, growB := weights[i]*(1+retsA[i]), (1-weights[i])*(1+retsB[i])
+		v *= growA + growB
+		if i+1 < len(retsA) {
+			traded[i] = math.Abs(weights[i+1]-growA/(growA+growB)) * v
+		}
+	}
+	return traded
+}
+
+func writeGlideTurnover(w io.Writer, rows []ReportRow, freq Frequency) {
+	total, rate := 0.0, 0.0
+	for _, r := range rows {
+		total += r.GlideTraded
+		rate += r.GlideTraded / r.Glide
+	}
+	fmt.Fprintf(w, "Glide path turnover: %.2f%% of value per %s period on average, %.2f traded in total (start 100)\n",
+		rate/float64(len(rows))*100, freq.Name, total)
+}
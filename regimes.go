@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ChangePoint is a period where the mean alpha shifted, found by binary segmentation.
+type ChangePoint struct {
+	Date       time.Time
+	Label      string
+	Before     float64 // mean alpha of the segment before
+	After      float64 // mean alpha of the segment from Date on
+	Confidence float64
+}
+
+// meanVar returns the mean and sample variance of values.
+func meanVar(values []float64) (float64, float64) {
+	m := 0.0
+	for _, v := range values {
+		m += v
+	}
+	m /= float64(len(values))
+	ss := 0.0
+	for _, v := range values {
+		ss += (v - m) * (v - m)
+	}
+	return m, ss / float64(len(values)-1)
+}
+
+// changePoints splits the alpha series where a Welch t-test between the two sides is most
+// significant and repeats on both halves while the confidence, Bonferroni-corrected for the
+// number of splits tried, reaches minConfidence. Segments keep at least minSegment periods.
+func changePoints(rows []ReportRow, minSegment int, minConfidence float64) []ChangePoint {
+	out := make([]ChangePoint, 0)
+	var split func(lo int, hi int)
+	split = func(lo int, hi int) {
+		if hi-lo < 2*minSegment {
+			return
+		}
+		alphas := make([]float64, hi-lo)
+		for i := range alphas {
+			alphas[i] = rows[lo+i].Alpha
+		}
+		best, bestT := -1, 0.0
+		for k := minSegment; k <= len(alphas)-minSegment; k++ {
+			m1, v1 := meanVar(alphas[:k])
+			m2, v2 := meanVar(alphas[k:])
+			se := math.Sqrt(v1/float64(k) + v2/float64(len(alphas)-k))
+			if se == 0 {
+				continue
+			}
+			if t := math.Abs(m1-m2) / se; t > bestT {
+				best, bestT = k, t
+			}
+		}
+		if best < 0 {
+			return
+		}
+		tried := float64(len(alphas) - 2*minSegment + 1)
+		confidence := math.Max(0, 1-math.Erfc(bestT/math.Sqrt2)*tried)
+		if confidence < minConfidence {
+			return
+		}
+		before, _ := meanVar(alphas[:best])
+		after, _ := meanVar(alphas[best:])
+		r := rows[lo+best]
+		out = append(out, ChangePoint{Date: r.Period, Label: r.Date, Before: before, After: after, Confidence: confidence})
+		split(lo, lo+best)
+		split(lo+best, hi)
+	}
+	split(0, len(rows))
+	sort.Slice(out, func(i, j int) bool { return out[i].Date.Before(out[j].Date) })
+	return out
+}
+
+// regimeTable renders the change points as an HTML table.
+func regimeTable(points []ChangePoint) string {
+	w := &bytes.Buffer{}
+	_, _ = w.WriteString("<h2>Alpha regime changes</h2>\n<table>\n<thead><tr><th>From</th><th>Mean alpha before</th><th>Mean alpha after</th><th>Confidence</th></tr></thead>\n<tbody>\n")
+	for _, c := range points {
+		_, _ = fmt.Fprintf(w, "<tr><td>%s</td><td>%.5f</td><td>%.5f</td><td>%.1f%%</td></tr>\n", c.Label, c.Before, c.After, c.Confidence*100)
+	}
+	_, _ = w.WriteString("</tbody>\n</table>\n")
+	return w.String()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// reportFixture is an analysis of synthetic returns, so the golden reports do not depend on
+// downloaded prices.
+type reportFixture struct {
+	Name   string
+	Res    Result
+	Charts ChartOptions
+}
+
+func reportFixtures(t testing.TB) []reportFixture {
+	t.Helper()
+	metrics, err := parseMetrics("te,ir,sharpe,maxdd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	build := func(p Params, count int, step func(time.Time) time.Time) Result {
+		dates := make([]time.Time, count)
+		retsE := make([]float64, count)
+		retsI := make([]float64, count)
+		d := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := range dates {
+			d = step(d)
+			dates[i] = d
+			retsI[i] = 0.01*math.Sin(float64(i)/3) + 0.004
+			retsE[i] = retsI[i] + 0.002*math.Cos(float64(i))
+		}
+		res, err := Analyze(p, dates, retsE, retsI, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	fixtures := []reportFixture{
+		{"monthly", build(Params{
+			ETF:        "VWCE.DE",
+			Index:      "^990100-USD-STRD",
+			StartDate:  "2020-01-01",
+			Interval:   "1mo",
+			Freq:       monthlyFrequency,
+			LifeWeight: 0.8,
+			Glide:      []float64{0.9, 0.6},
+			Metrics:    metrics,
+		}, 30, func(d time.Time) time.Time { return d.AddDate(0, 1, 0) }), defaultChartOptions()},
+		{"weekly", build(Params{
+			ETF:        "SPY",
+			Index:      "^GSPC",
+			StartDate:  "2020-01-01",
+			EndDate:    "2020-12-31",
+			Interval:   "1wk",
+			Freq:       weeklyFrequency,
+			LifeWeight: 0.6,
+			Glide:      []float64{0.6, 0.9, 0.7},
+			Tent:       BondTent{Retire: time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC), Depth: 0.2, Years: 0.5},
+			Metrics:    metrics,
+		}, 52, func(d time.Time) time.Time { return d.AddDate(0, 0, 7) }), defaultChartOptions()},
+	}
+
+	// The monthly report also carries the strategy sections built outside Analyze.
+	monthly := fixtures[0].Res
+	strategies := [][]float64{monthly.ETFReturns, monthly.IndexReturns, monthly.LifeReturns, monthly.GlideReturns}
+	lifecycle := lifecycleReport(monthly.Dates, strategies, time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC), 10, 8)
+	fixtures[0].Charts.Lifecycle = &lifecycle
+	return fixtures
+}
+
+// checkGolden compares got with testdata/name, rewriting the file instead when -update is set.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file; run go test -update and review the diff", path)
+	}
+}
+
+func TestHTMLReportGolden(t *testing.T) {
+	for _, f := range reportFixtures(t) {
+		t.Run(f.Name, func(t *testing.T) {
+			columns, err := parseColumns(defaultColumns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var csvData bytes.Buffer
+			writeCSV(&csvData, f.Res.Rows, columns, "", true)
+			var page bytes.Buffer
+			if err := writeHTMLReport(&page, f.Res, csvData.Bytes(), chartJSCDN, f.Charts); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "report_"+f.Name+".html", page.Bytes())
+		})
+	}
+}
+
+func TestLaTeXTablesGolden(t *testing.T) {
+	for _, f := range reportFixtures(t) {
+		t.Run(f.Name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := renderLaTeXTables(&out, f.Res); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "report_"+f.Name+".tex", out.Bytes())
+		})
+	}
+}
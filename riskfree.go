@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// periodRate converts an annual rate into the equivalent rate per period.
+func periodRate(annual float64, periodsPerYear float64) float64 {
+	return math.Pow(1+annual, 1/periodsPerYear) - 1
+}
+
+// riskFreeRates turns a resampled yield series quoted in percent per year (such as ^IRX) into
+// per-period rates. Each period earns the yield observed at the close of the period before.
+func riskFreeRates(yields map[time.Time]float64, periodsPerYear float64) map[time.Time]float64 {
+	dates := make([]time.Time, 0, len(yields))
+	for d := range yields {
+		dates = append(dates, d)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	out := make(map[time.Time]float64, len(dates))
+	for i := 1; i < len(dates); i++ {
+		out[dates[i]] = periodRate(yields[dates[i-1]]/100, periodsPerYear)
+	}
+	return out
+}
+
+// riskFreeFor lines up per-period risk-free rates with the given periods, using the constant
+// rate where the series has no value.
+func riskFreeFor(periods []time.Time, rates map[time.Time]float64, constant float64) ([]float64, int) {
+	out := make([]float64, len(periods))
+	missing := 0
+	for i, d := range periods {
+		v, ok := rates[d]
+		if !ok {
+			v = constant
+			missing++
+		}
+		out[i] = v
+	}
+	return out, missing
+}
+
+// subtractRiskFree turns returns into excess returns over the aligned risk-free rates.
+func subtractRiskFree(returns []float64, riskFree []float64) {
+	for i := range returns {
+		returns[i] -= riskFree[i]
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// percentile returns the p-th percentile (0-1) of sorted values by linear interpolation.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	pos := p * float64(len(sorted)-1)
+	lo := int(pos)
+	if lo+1 >= len(sorted) {
+		return sorted[lo]
+	}
+	return sorted[lo] + (pos-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
+
+// rollingFinals returns the final value of 100 invested over every window of n consecutive
+// periods, for each strategy. glideFor returns the glide weights of the window starting at
+// period start.
+func rollingFinals(retsA []float64, retsB []float64, n int, lifeWeight float64, glideFor func(start int, n int) []float64) map[string][]float64 {
+	out := make(map[string][]float64)
+	for i := 0; i+n <= len(retsA); i++ {
+		a, b := retsA[i:i+n], retsB[i:i+n]
+		finals := map[string][]float64{
+			"ETF":          a,
+			"Index":        b,
+			"LifeStrategy": blendReturns(a, b, lifeWeight),
+			"GlidePath":    weightedReturns(a, b, glideFor(i, n)),
+		}
+		for name, rets := range finals {
+			cum := cumulative(100, rets)
+			out[name] = append(out[name], cum[len(cum)-1])
+		}
+	}
+	return out
+}
+
+// writeRollingReport prints the distribution of final values over all rolling windows of
+// years length.
+func writeRollingReport(w io.Writer, retsA []float64, retsB []float64, years int, freq Frequency, lifeWeight float64, glideFor func(start int, n int) []float64) {
+	n := int(float64(years) * freq.PeriodsPerYear)
+	finals := rollingFinals(retsA, retsB, n, lifeWeight, glideFor)
+	if len(finals) == 0 {
+		fmt.Fprintf(w, "Rolling %d-year windows: not enough data (%d periods, need %d)\n", years, len(retsA), n)
+		return
+	}
+	fmt.Fprintf(w, "Rolling %d-year windows: %d (final value of 100)\n", years, len(finals["ETF"]))
+	fmt.Fprintf(w, "%-14s %9s %9s %9s %9s %9s\n", "Strategy", "Min", "P10", "Median", "P90", "Max")
+	for _, cs := range chartSeries {
+		values := finals[cs.Name]
+		sort.Float64s(values)
+		fmt.Fprintf(w, "%-14s %9.2f %9.2f %9.2f %9.2f %9.2f\n", cs.Name,
+			values[0], percentile(values, 0.1), percentile(values, 0.5), percentile(values, 0.9), values[len(values)-1])
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// validateRoundStep checks that -round-weights divides 100% into whole increments.
+func validateRoundStep(step float64) error {
+	units := 1 / step
+	if step <= 0 || step > 0.5 || math.Abs(units-math.Round(units)) > 1e-9 {
+		return fmt.Errorf("round-weights must divide 1 into whole increments, e.g. 0.05 or 0.1")
+	}
+	return nil
+}
+
+// roundWeights rounds portfolio weights to multiples of step while keeping their sum at one:
+// every weight is rounded down and the remaining increments go to the largest remainders.
+func roundWeights(weights []float64, step float64) []float64 {
+	units := make([]float64, len(weights))
+	order := make([]int, len(weights))
+	left := math.Round(1 / step)
+	for i, w := range weights {
+		units[i] = math.Floor(w/step + 1e-9)
+		left -= units[i]
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return weights[order[a]]/step-units[order[a]] > weights[order[b]]/step-units[order[b]]
+	})
+	for k := 0; left > 0 && k < len(order); k++ {
+		units[order[k]]++
+		left--
+	}
+	out := make([]float64, len(weights))
+	for i, u := range units {
+		out[i] = u * step
+	}
+	return out
+}
+
+// roundGlide rounds every period's ETF weight to the nearest multiple of step, turning a
+// smooth glide path into a few discrete steps.
+func roundGlide(weights []float64, step float64) []float64 {
+	out := make([]float64, len(weights))
+	for i, w := range weights {
+		out[i] = math.Round(w/step) * step
+	}
+	return out
+}
+
+// weightChanges counts the periods whose weight differs from the period before.
+func weightChanges(weights []float64) int {
+	n := 0
+	for i := 1; i < len(weights); i++ {
+		if math.Abs(weights[i]-weights[i-1]) > 1e-9 {
+			n++
+		}
+	}
+	return n
+}
+
+// writeRoundingReport prints how rounding the weights changed a strategy's track.
+func writeRoundingReport(w io.Writer, name string, exact []float64, rounded []float64, periodsPerYear float64) {
+	stats := func(returns []float64) (float64, float64, float64, float64) {
+		in := MetricInput{Returns: returns, PeriodsPerYear: periodsPerYear}
+		cum := cumulative(100, returns)
+		return cum[len(cum)-1], metricRegistry["cagr"].Compute(in), metricRegistry["vol"].Compute(in), maxDrawdown(returns)
+	}
+	fe, ce, ve, de := stats(exact)
+	fr, cr, vr, dr := stats(rounded)
+	fmt.Fprintf(w, "Rounded %s: final %.2f -> %.2f, CAGR %.2f%% -> %.2f%%, vol %.2f%% -> %.2f%%, max drawdown %.2f%% -> %.2f%%\n",
+		name, fe, fr, ce*100, cr*100, ve*100, vr*100, de*100, dr*100)
+}
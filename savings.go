@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SavingsRow holds, for one contribution amount, the years each strategy took to reach the
+// target (NaN when it never did).
+type SavingsRow struct {
+	Contribution float64
+	Years        []float64
+}
+
+// parseAmounts parses a comma separated list of positive amounts.
+func parseAmounts(value string) ([]float64, error) {
+	out := make([]float64, 0)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid amount %q", part)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// yearsToTarget invests contribution at the start of every period from an empty portfolio
+// and returns the years until the value first reaches target.
+func yearsToTarget(returns []float64, contribution float64, target float64, periodsPerYear float64) float64 {
+	v := 0.0
+	for i, r := range returns {
+		v = (v + contribution) * (1 + r)
+		if v >= target {
+			return float64(i+1) / periodsPerYear
+		}
+	}
+	return math.NaN()
+}
+
+// savingsGrid runs yearsToTarget for every contribution and strategy; strategies holds
+// per-period returns in chartSeries order.
+func savingsGrid(strategies [][]float64, amounts []float64, target float64, periodsPerYear float64) []SavingsRow {
+	out := make([]SavingsRow, len(amounts))
+	for i, c := range amounts {
+		out[i] = SavingsRow{Contribution: c, Years: make([]float64, len(strategies))}
+		for j, rets := range strategies {
+			out[i].Years[j] = yearsToTarget(rets, c, target, periodsPerYear)
+		}
+	}
+	return out
+}
+
+// savingsHeatmap renders the grid as an HTML table shaded from green (fastest) to red
+// (slowest); cells that never reached the target are grey.
+func savingsHeatmap(grid []SavingsRow, target float64) string {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, row := range grid {
+		for _, y := range row.Years {
+			if !math.IsNaN(y) {
+				lo, hi = math.Min(lo, y), math.Max(hi, y)
+			}
+		}
+	}
+	w := &bytes.Buffer{}
+	_, _ = fmt.Fprintf(w, "<h2>Years to reach %.0f by contribution per period</h2>\n", target)
+	_, _ = w.WriteString("<table>\n<thead><tr><th>Contribution</th>")
+	for _, cs := range chartSeries {
+		_, _ = fmt.Fprintf(w, "<th>%s</th>", cs.Name)
+	}
+	_, _ = w.WriteString("</tr></thead>\n<tbody>\n")
+	for _, row := range grid {
+		_, _ = fmt.Fprintf(w, "<tr><td>%.2f</td>", row.Contribution)
+		for _, y := range row.Years {
+			if math.IsNaN(y) {
+				_, _ = w.WriteString("<td style=\"background:#eee\">not reached</td>")
+				continue
+			}
+			t := 0.0
+			if hi > lo {
+				t = (y - lo) / (hi - lo)
+			}
+			_, _ = fmt.Fprintf(w, "<td style=\"background:hsl(%.0f,70%%,80%%)\">%.1f</td>", 120*(1-t), y)
+		}
+		_, _ = w.WriteString("</tr>\n")
+	}
+	_, _ = w.WriteString("</tbody>\n</table>\n")
+	return w.String()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// SolverResult is the allocation with the lowest max drawdown that reached the target.
+type SolverResult struct {
+	Start, End float64
+	Final      float64
+	MaxDD      float64
+}
+
+// contributionPath grows an initial 100 by the given returns, investing contribution at the
+// start of every period, and returns the final value and the max drawdown of the returns.
+func contributionPath(returns []float64, contribution float64) (float64, float64) {
+	v := 100.0
+	for _, r := range returns {
+		v = (v + contribution) * (1 + r)
+	}
+	return v, maxDrawdown(returns)
+}
+
+// glideReturns blends the aligned returns along a linear ETF weight path.
+func glideReturns(retsA []float64, retsB []float64, start float64, end float64) []float64 {
+	return weightedReturns(retsA, retsB, pathWeights(len(retsA), []float64{start, end}))
+}
+
+// solveTarget searches constant ETF weights in 1% steps and glide start/end pairs in 5% steps
+// for the allocations that reached target with the lowest max drawdown. ok is false when
+// nothing reached it.
+func solveTarget(retsA []float64, retsB []float64, target float64, contribution float64) (constant SolverResult, glide SolverResult, ok bool) {
+	constant.MaxDD, glide.MaxDD = math.Inf(1), math.Inf(1)
+	for i := 0; i <= 100; i++ {
+		w := float64(i) / 100
+		final, dd := contributionPath(blendReturns(retsA, retsB, w), contribution)
+		if final >= target && dd < constant.MaxDD {
+			constant = SolverResult{Start: w, End: w, Final: final, MaxDD: dd}
+		}
+	}
+	for i := 0; i <= 20; i++ {
+		for j := 0; j <= 20; j++ {
+			s, e := float64(i)/20, float64(j)/20
+			final, dd := contributionPath(glideReturns(retsA, retsB, s, e), contribution)
+			if final >= target && dd < glide.MaxDD {
+				glide = SolverResult{Start: s, End: e, Final: final, MaxDD: dd}
+			}
+		}
+	}
+	return constant, glide, !math.IsInf(constant.MaxDD, 1) || !math.IsInf(glide.MaxDD, 1)
+}
+
+// writeSolverReport prints the solver answer and how the final value and drawdown respond to
+// moving the constant weight by 5 percentage points either way.
+func writeSolverReport(w io.Writer, retsA []float64, retsB []float64, target float64, contribution float64) {
+	constant, glide, ok := solveTarget(retsA, retsB, target, contribution)
+	if !ok {
+		fmt.Fprintf(w, "Solver: no allocation reached %.2f (contribution %.2f per period)\n", target, contribution)
+		return
+	}
+	if math.IsInf(constant.MaxDD, 1) {
+		fmt.Fprintf(w, "Solver: no constant ETF weight reached %.2f\n", target)
+	} else {
+		fmt.Fprintf(w, "Solver: constant ETF weight %.2f reached %.2f with max drawdown %.2f%%\n", constant.Start, constant.Final, constant.MaxDD*100)
+		for _, d := range []float64{-0.05, 0.05} {
+			wt := math.Round((constant.Start+d)*100) / 100
+			if wt < 0 || wt > 1 {
+				continue
+			}
+			final, dd := contributionPath(blendReturns(retsA, retsB, wt), contribution)
+			fmt.Fprintf(w, "Solver:   weight %.2f -> final %.2f, max drawdown %.2f%%\n", wt, final, dd*100)
+		}
+	}
+	if math.IsInf(glide.MaxDD, 1) {
+		fmt.Fprintf(w, "Solver: no glide path reached %.2f\n", target)
+	} else {
+		fmt.Fprintf(w, "Solver: glide path %.2f -> %.2f reached %.2f with max drawdown %.2f%%\n", glide.Start, glide.End, glide.Final, glide.MaxDD*100)
+	}
+}
+
+// solveDrawdownCap searches the same constant weights and glide paths as solveTarget for the
+// ones with the highest final value whose max drawdown stayed within ceiling. ok is false when
+// none did.
+func solveDrawdownCap(retsA []float64, retsB []float64, ceiling float64) (constant SolverResult, glide SolverResult, ok bool) {
+	constant.Final, glide.Final = math.Inf(-1), math.Inf(-1)
+	for i := 0; i <= 100; i++ {
+		w := float64(i) / 100
+		final, dd := contributionPath(blendReturns(retsA, retsB, w), 0)
+		if dd <= ceiling && final > constant.Final {
+			constant = SolverResult{Start: w, End: w, Final: final, MaxDD: dd}
+		}
+	}
+	for i := 0; i <= 20; i++ {
+		for j := 0; j <= 20; j++ {
+			s, e := float64(i)/20, float64(j)/20
+			final, dd := contributionPath(glideReturns(retsA, retsB, s, e), 0)
+			if dd <= ceiling && final > glide.Final {
+				glide = SolverResult{Start: s, End: e, Final: final, MaxDD: dd}
+			}
+		}
+	}
+	return constant, glide, !math.IsInf(constant.Final, -1) || !math.IsInf(glide.Final, -1)
+}
+
+// writeDrawdownCapReport prints the best constant weight and glide path within the drawdown
+// ceiling with their annualized returns.
+func writeDrawdownCapReport(w io.Writer, retsA []float64, retsB []float64, ceiling float64, periodsPerYear float64) {
+	constant, glide, ok := solveDrawdownCap(retsA, retsB, ceiling)
+	if !ok {
+		fmt.Fprintf(w, "Drawdown cap: no allocation kept its max drawdown within %.2f%%\n", ceiling*100)
+		return
+	}
+	cagr := func(final float64) float64 { return math.Pow(final/100, periodsPerYear/float64(len(retsA))) - 1 }
+	if math.IsInf(constant.Final, -1) {
+		fmt.Fprintf(w, "Drawdown cap: no constant ETF weight kept its max drawdown within %.2f%%\n", ceiling*100)
+	} else {
+		fmt.Fprintf(w, "Drawdown cap: constant ETF weight %.2f has the best CAGR %.2f%% with max drawdown %.2f%% <= %.2f%%\n",
+			constant.Start, cagr(constant.Final)*100, constant.MaxDD*100, ceiling*100)
+	}
+	if math.IsInf(glide.Final, -1) {
+		fmt.Fprintf(w, "Drawdown cap: no glide path kept its max drawdown within %.2f%%\n", ceiling*100)
+	} else {
+		fmt.Fprintf(w, "Drawdown cap: glide path %.2f -> %.2f has the best CAGR %.2f%% with max drawdown %.2f%% <= %.2f%%\n",
+			glide.Start, glide.End, cagr(glide.Final)*100, glide.MaxDD*100, ceiling*100)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SpliceSpec joins the histories of a renamed or merged fund. The spec
+// "NEW.MI<2021-07-01<OLD.MI" uses NEW.MI from 2021-07-01 and OLD.MI before; further
+// "<date<SYMBOL" pairs extend the chain backwards.
+type SpliceSpec struct {
+	Symbols []string
+	Dates   []time.Time
+}
+
+func parseSplice(spec string) (SpliceSpec, error) {
+	parts := strings.Split(spec, "<")
+	if len(parts)%2 == 0 {
+		return SpliceSpec{}, fmt.Errorf("invalid splice %q (want NEW<YYYY-MM-DD<OLD)", spec)
+	}
+	var s SpliceSpec
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if i%2 == 0 {
+			if p == "" {
+				return SpliceSpec{}, fmt.Errorf("invalid splice %q: empty symbol", spec)
+			}
+			s.Symbols = append(s.Symbols, p)
+			continue
+		}
+		d, err := time.Parse("2006-01-02", p)
+		if err != nil {
+			return SpliceSpec{}, fmt.Errorf("invalid splice date %q: %w", p, err)
+		}
+		if len(s.Dates) > 0 && !d.Before(s.Dates[len(s.Dates)-1]) {
+			return SpliceSpec{}, fmt.Errorf("invalid splice %q: dates must go back in time", spec)
+		}
+		s.Dates = append(s.Dates, d)
+	}
+	return s, nil
+}
+
+// loadSpliced loads every ticker of the spec and joins them into one continuous series
+// named after the newest ticker. Older segments are rescaled so the price is continuous at
+// each splice point. The returned events mark the splice points for the report.
+func loadSpliced(spec SpliceSpec, load func(symbol string) (Series, error)) (Series, []ChartEvent, error) {
+	combined, err := load(spec.Symbols[0])
+	if err != nil {
+		return Series{}, nil, err
+	}
+	if len(spec.Dates) == 0 {
+		return combined, nil, nil
+	}
+	combined = truncateSeries(combined, spec.Dates[0], clock.Now().AddDate(1, 0, 0))
+
+	events := make([]ChartEvent, 0, len(spec.Dates))
+	for i, boundary := range spec.Dates {
+		older, err := load(spec.Symbols[i+1])
+		if err != nil {
+			return Series{}, nil, err
+		}
+		if len(combined.Points) == 0 {
+			return Series{}, nil, fmt.Errorf("splice %s: no data after %s", spec.Symbols[i], boundary.Format("2006-01-02"))
+		}
+		first := combined.Points[0]
+		// The older ticker covers the bars from the next splice date back, or all of them for
+		// the oldest one.
+		var from time.Time
+		if i+1 < len(spec.Dates) {
+			from = spec.Dates[i+1]
+		}
+
+		// Scale by the older ticker's last close on or before the first newer bar.
+		ratio := 0.0
+		prefix := make([]PricePoint, 0, len(older.Points))
+		for _, p := range older.Points {
+			if p.Date.After(first.Date) {
+				break
+			}
+			ratio = first.Close / p.Close
+			if p.Date.Before(first.Date) && !p.Date.Before(from) {
+				prefix = append(prefix, p)
+			}
+		}
+		if ratio == 0 || !older.Points[0].Date.Before(boundary) {
+			return Series{}, nil, fmt.Errorf("splice %s: no data before %s", spec.Symbols[i+1], boundary.Format("2006-01-02"))
+		}
+		if len(prefix) == 0 {
+			return Series{}, nil, fmt.Errorf("splice %s: no data between %s and %s", spec.Symbols[i+1], from.Format("2006-01-02"), boundary.Format("2006-01-02"))
+		}
+		for j := range prefix {
+			prefix[j].Close *= ratio
+		}
+		combined.Points = append(prefix, combined.Points...)
+		events = append(events, ChartEvent{Date: boundary, Label: fmt.Sprintf("%s → %s", spec.Symbols[i+1], spec.Symbols[i])})
+	}
+	combined.Symbol = spec.Symbols[0]
+	return combined, events, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadSplicedChain(t *testing.T) {
+	// Three tickers overlapping over all of 2020, one bar on the first of each month.
+	series := func(symbol string, close float64) Series {
+		s := Series{Symbol: symbol}
+		for m := 1; m <= 12; m++ {
+			s.Points = append(s.Points, PricePoint{Date: time.Date(2020, time.Month(m), 1, 0, 0, 0, 0, time.UTC), Close: close})
+		}
+		return s
+	}
+	data := map[string]Series{"C": series("C", 10), "B": series("B", 20), "A": series("A", 40)}
+	// B's March close lies before its splice date and must come from A instead.
+	data["B"].Points[2].Close = 80
+	load := func(symbol string) (Series, error) { return data[symbol], nil }
+
+	spec, err := parseSplice("C<2020-09-01<B<2020-05-01<A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, events, err := loadSpliced(spec, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Points) != 12 || len(events) != 2 {
+		t.Fatalf("got %d points and %d events, want 12 and 2", len(got.Points), len(events))
+	}
+	for _, p := range got.Points {
+		if p.Close != 10 {
+			t.Errorf("%s: close %.2f, want 10 after rescaling", p.Date.Format("2006-01-02"), p.Close)
+		}
+	}
+
+	// A chain whose older dates fall before the data is rejected.
+	spec, err = parseSplice("C<2020-09-01<B<2019-05-01<A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := loadSpliced(spec, load); err == nil {
+		t.Error("splice date before the data: want an error")
+	}
+	if _, err := parseSplice("C<2020-05-01<B<2020-09-01<A"); err == nil {
+		t.Error("splice dates out of order: want an error")
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// parseSymbols splits a comma separated symbol list.
+func parseSymbols(value string) []string {
+	out := make([]string, 0)
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// projectSimplex returns the Euclidean projection of v onto {w : w >= 0, sum(w) = 1}.
+func projectSimplex(v []float64) []float64 {
+	u := append([]float64(nil), v...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(u)))
+	sum, theta := 0.0, 0.0
+	for i, x := range u {
+		sum += x
+		if t := (sum - 1) / float64(i+1); x-t > 0 {
+			theta = t
+		}
+	}
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = math.Max(x-theta, 0)
+	}
+	return out
+}
+
+// styleWeights solves the returns-based style analysis of y on the columns xs: least squares
+// with non-negative weights summing to one, by projected gradient descent.
+func styleWeights(y []float64, xs [][]float64) []float64 {
+	k := len(xs)
+	w := make([]float64, k)
+	for j := range w {
+		w[j] = 1 / float64(k)
+	}
+	// The trace of X'X bounds its largest eigenvalue, which keeps the step stable.
+	trace := 0.0
+	for _, x := range xs {
+		for _, v := range x {
+			trace += v * v
+		}
+	}
+	if trace == 0 {
+		return w
+	}
+	step := 1 / trace
+	grad := make([]float64, k)
+	for iter := 0; iter < 5000; iter++ {
+		for j := range grad {
+			grad[j] = 0
+		}
+		for t := range y {
+			resid := -y[t]
+			for j := range xs {
+				resid += w[j] * xs[j][t]
+			}
+			for j := range xs {
+				grad[j] += resid * xs[j][t]
+			}
+		}
+		next := make([]float64, k)
+		for j := range w {
+			next[j] = w[j] - step*grad[j]
+		}
+		next = projectSimplex(next)
+		moved := 0.0
+		for j := range w {
+			moved += math.Abs(next[j] - w[j])
+		}
+		w = next
+		if moved < 1e-10 {
+			break
+		}
+	}
+	return w
+}
+
+// StyleWindow holds the style weights estimated over the window ending at Date.
+type StyleWindow struct {
+	Date    time.Time
+	Weights []float64
+}
+
+// rollingStyle estimates style weights over every window of n periods.
+func rollingStyle(dates []time.Time, y []float64, xs [][]float64, n int) []StyleWindow {
+	out := make([]StyleWindow, 0)
+	for end := n; end <= len(y); end++ {
+		cols := make([][]float64, len(xs))
+		for j, x := range xs {
+			cols[j] = x[end-n : end]
+		}
+		out = append(out, StyleWindow{Date: dates[end-1], Weights: styleWeights(y[end-n:end], cols)})
+	}
+	return out
+}
+
+// alignBenchmarks keeps the dates on which every benchmark has a return and returns the ETF
+// returns and benchmark columns on those dates.
+func alignBenchmarks(dates []time.Time, rets []float64, benchDates [][]time.Time, benchRets [][]float64) ([]time.Time, []float64, [][]float64) {
+	index := make([]map[time.Time]float64, len(benchDates))
+	for j := range benchDates {
+		index[j] = make(map[time.Time]float64, len(benchDates[j]))
+		for i, d := range benchDates[j] {
+			index[j][d] = benchRets[j][i]
+		}
+	}
+	outDates := make([]time.Time, 0, len(dates))
+	outRets := make([]float64, 0, len(dates))
+	cols := make([][]float64, len(benchDates))
+	row := make([]float64, len(benchDates))
+	for i, d := range dates {
+		ok := true
+		for j := range index {
+			if row[j], ok = index[j][d]; !ok {
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		outDates = append(outDates, d)
+		outRets = append(outRets, rets[i])
+		for j := range cols {
+			cols[j] = append(cols[j], row[j])
+		}
+	}
+	return outDates, outRets, cols
+}
+
+// styleLines converts rolling style weights into one chart line per benchmark, in percent.
+func styleLines(symbols []string, windows []StyleWindow, freq Frequency) []ChartLine {
+	lines := make([]ChartLine, len(symbols))
+	for j, s := range symbols {
+		lines[j] = ChartLine{Label: s, Values: make(map[string]float64, len(windows))}
+		for _, win := range windows {
+			lines[j].Values[freq.Label(win.Date)] = win.Weights[j] * 100
+		}
+	}
+	return lines
+}
+
+// formatWeights renders style weights as "SYM 60.0%, SYM 40.0%".
+func formatWeights(symbols []string, weights []float64) string {
+	parts := make([]string, len(symbols))
+	for j, s := range symbols {
+		parts[j] = fmt.Sprintf("%s %.1f%%", s, weights[j]*100)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// StyleDrift is a window whose style weights moved by more than the threshold compared with
+// the window one full window length earlier.
+type StyleDrift struct {
+	Date  time.Time
+	Shift float64
+	From  []float64
+	To    []float64
+}
+
+// styleDrifts flags the start of every episode in which the total weight shift (half the sum
+// of absolute weight changes) against the non-overlapping previous window exceeds threshold.
+func styleDrifts(windows []StyleWindow, n int, threshold float64) []StyleDrift {
+	out := make([]StyleDrift, 0)
+	drifting := false
+	for i := n; i < len(windows); i++ {
+		prev, cur := windows[i-n].Weights, windows[i].Weights
+		shift := 0.0
+		for j := range cur {
+			shift += math.Abs(cur[j] - prev[j])
+		}
+		shift /= 2
+		if shift > threshold && !drifting {
+			out = append(out, StyleDrift{Date: windows[i].Date, Shift: shift, From: prev, To: cur})
+		}
+		drifting = shift > threshold
+	}
+	return out
+}
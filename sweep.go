@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// SweepPoint summarizes the ETF/index blend at one ETF weight.
+type SweepPoint struct {
+	Weight float64
+	Final  float64
+	CAGR   float64
+	Vol    float64
+	MaxDD  float64
+}
+
+// weightSweep evaluates blends of the aligned returns from an all-index (0) to an all-ETF (1)
+// portfolio in increments of step.
+func weightSweep(retsA []float64, retsB []float64, step float64, periodsPerYear float64) []SweepPoint {
+	n := int(1/step + 0.5)
+	out := make([]SweepPoint, 0, n+1)
+	for i := 0; i <= n; i++ {
+		out = append(out, sweepPoint(retsA, retsB, float64(i)/float64(n), periodsPerYear))
+	}
+	return out
+}
+
+func sweepPoint(retsA []float64, retsB []float64, w float64, periodsPerYear float64) SweepPoint {
+	in := MetricInput{Returns: blendReturns(retsA, retsB, w), PeriodsPerYear: periodsPerYear}
+	cum := cumulative(100, in.Returns)
+	return SweepPoint{
+		Weight: w,
+		Final:  cum[len(cum)-1],
+		CAGR:   metricRegistry["cagr"].Compute(in),
+		Vol:    metricRegistry["vol"].Compute(in),
+		MaxDD:  metricRegistry["maxdd"].Compute(in),
+	}
+}
+
+// FrontierMark is a labelled blend highlighted on the frontier chart.
+type FrontierMark struct {
+	Label string
+	Point SweepPoint
+}
+
+// frontierJS returns the JavaScript drawing the realized volatility/CAGR frontier on the
+// frontierChart canvas, with each mark as a separate highlighted point.
+func frontierJS(points []SweepPoint, marks []FrontierMark) string {
+	var b strings.Builder
+	b.WriteString("new Chart(document.getElementById('frontierChart'),{type:'scatter',data:{datasets:[{label:'ETF weight',data:[")
+	for i, p := range points {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		_, _ = fmt.Fprintf(&b, "{x:%.3f,y:%.3f,w:%.2f}", p.Vol*100, p.CAGR*100, p.Weight)
+	}
+	_, _ = fmt.Fprintf(&b, "],showLine:true,borderColor:'%s',backgroundColor:'%s',pointRadius:3}", linePalette[2], linePalette[2])
+	for i, m := range marks {
+		color := linePalette[(i+3)%len(linePalette)]
+		_, _ = fmt.Fprintf(&b, ",{label:%q,data:[{x:%.3f,y:%.3f,w:%.2f}],borderColor:'%s',backgroundColor:'%s',pointRadius:7,pointStyle:'rectRot'}",
+			html.EscapeString(m.Label), m.Point.Vol*100, m.Point.CAGR*100, m.Point.Weight, color, color)
+	}
+	b.WriteString("]},options:{plugins:{legend:{position:'bottom'},tooltip:{callbacks:{label:c=>c.dataset.label+': weight '+c.raw.w.toFixed(2)+', vol '+c.raw.x.toFixed(2)+'%, CAGR '+c.raw.y.toFixed(2)+'%'}}},")
+	b.WriteString("scales:{x:{title:{display:true,text:'Volatility (%)'}},y:{title:{display:true,text:'CAGR (%)'}}}}});\n")
+	return b.String()
+}
+
+// sweepJS returns the JavaScript drawing the weight sweep on the sweepChart canvas: final value
+// on the left axis and CAGR, volatility and max drawdown in percent on the right one.
+func sweepJS(points []SweepPoint) string {
+	var labels, final, cagr, vol, dd strings.Builder
+	for i, p := range points {
+		if i > 0 {
+			labels.WriteString(",")
+			final.WriteString(",")
+			cagr.WriteString(",")
+			vol.WriteString(",")
+			dd.WriteString(",")
+		}
+		_, _ = fmt.Fprintf(&labels, "'%.2f'", p.Weight)
+		_, _ = fmt.Fprintf(&final, "%.2f", p.Final)
+		_, _ = fmt.Fprintf(&cagr, "%.3f", p.CAGR*100)
+		_, _ = fmt.Fprintf(&vol, "%.3f", p.Vol*100)
+		_, _ = fmt.Fprintf(&dd, "%.3f", p.MaxDD*100)
+	}
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "new Chart(document.getElementById('sweepChart'),{type:'line',data:{labels:[%s],datasets:[", labels.String())
+	_, _ = fmt.Fprintf(&b, "{label:'Final value',data:[%s],borderColor:'%s',yAxisID:'y'},", final.String(), linePalette[0])
+	_, _ = fmt.Fprintf(&b, "{label:'CAGR (%%)',data:[%s],borderColor:'%s',yAxisID:'y1'},", cagr.String(), linePalette[1])
+	_, _ = fmt.Fprintf(&b, "{label:'Volatility (%%)',data:[%s],borderColor:'%s',yAxisID:'y1'},", vol.String(), linePalette[2])
+	_, _ = fmt.Fprintf(&b, "{label:'Max drawdown (%%)',data:[%s],borderColor:'%s',yAxisID:'y1'}]},", dd.String(), linePalette[3])
+	b.WriteString("options:{plugins:{legend:{position:'bottom'}},scales:{x:{title:{display:true,text:'ETF weight'}},")
+	b.WriteString("y:{position:'left',title:{display:true,text:'Final value (base 100)'}},")
+	b.WriteString("y1:{position:'right',grid:{drawOnChartArea:false},title:{display:true,text:'%'}}}}});\n")
+	return b.String()
+}
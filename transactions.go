@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transaction is one trade from a broker export; sells have a negative quantity.
+type Transaction struct {
+	Date     time.Time
+	Symbol   string
+	Quantity float64
+	Price    float64
+	Fees     float64
+}
+
+// loadTransactions reads a date,symbol,quantity,price[,fees] CSV. Dates are YYYY-MM-DD and a
+// header row is skipped.
+func loadTransactions(path string) ([]Transaction, error) {
+	f, err := outputFS.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open transactions: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return parseTransactions(f)
+}
+
+func parseTransactions(r io.Reader) ([]Transaction, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	out := make([]Transaction, 0)
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read transactions: %w", err)
+		}
+		if len(rec) < 4 {
+			return nil, fmt.Errorf("transactions line %d: want date,symbol,quantity,price[,fees]", line)
+		}
+		d, err := time.Parse("2006-01-02", rec[0])
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("transactions line %d: invalid date %q", line, rec[0])
+		}
+		tx := Transaction{Date: d, Symbol: strings.TrimSpace(rec[1])}
+		fields := []*float64{&tx.Quantity, &tx.Price, &tx.Fees}
+		for i, field := range rec[2:min(len(rec), 5)] {
+			v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return nil, fmt.Errorf("transactions line %d: invalid number %q", line, field)
+			}
+			*fields[i] = v
+		}
+		out = append(out, tx)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("transactions: no trades")
+	}
+	return out, nil
+}
+
+// transactionSymbols lists the distinct symbols traded.
+func transactionSymbols(txs []Transaction) []string {
+	seen := make(map[string]bool)
+	out := make([]string, 0)
+	for _, tx := range txs {
+		if !seen[tx.Symbol] {
+			seen[tx.Symbol] = true
+			out = append(out, tx.Symbol)
+		}
+	}
+	return out
+}
+
+// ActualTrack is the real portfolio rebuilt from the transactions over the aligned periods.
+type ActualTrack struct {
+	Values    []float64 // holdings at each period's close
+	Flows     []float64 // net cash invested in each period, negative for withdrawals
+	Invested  float64
+	Withdrawn float64
+	Skipped   int // trades after the last period
+}
+
+// actualTrack replays the trades onto the periods, valuing holdings at the period closes of
+// each symbol and carrying the last close over periods without one. Trades before the first
+// period count in the first one.
+func actualTrack(txs []Transaction, dates []time.Time, closes map[string]map[time.Time]float64, freq Frequency) ActualTrack {
+	track := ActualTrack{Values: make([]float64, len(dates)), Flows: make([]float64, len(dates))}
+	trades := make([][]Transaction, len(dates))
+	for _, tx := range txs {
+		key := freq.Key(tx.Date)
+		i := sort.Search(len(dates), func(i int) bool { return !dates[i].Before(key) })
+		if i == len(dates) {
+			track.Skipped++
+			continue
+		}
+		trades[i] = append(trades[i], tx)
+	}
+	held := make(map[string]float64)
+	last := make(map[string]float64)
+	for i, d := range dates {
+		for _, tx := range trades[i] {
+			held[tx.Symbol] += tx.Quantity
+			flow := tx.Quantity*tx.Price + tx.Fees
+			track.Flows[i] += flow
+			if flow > 0 {
+				track.Invested += flow
+			} else {
+				track.Withdrawn -= flow
+			}
+			if _, ok := last[tx.Symbol]; !ok {
+				last[tx.Symbol] = tx.Price
+			}
+		}
+		for sym, qty := range held {
+			if c, ok := closes[sym][d]; ok {
+				last[sym] = c
+			}
+			track.Values[i] += qty * last[sym]
+		}
+	}
+	return track
+}
+
+// flowPath grows the cash flows by the given returns, investing each period's flow at its
+// start, and returns the final value.
+func flowPath(returns []float64, flows []float64) float64 {
+	v := 0.0
+	for i, r := range returns {
+		v = (v + flows[i]) * (1 + r)
+	}
+	return v
+}
+
+// writeTransactionReport compares the real portfolio with each strategy, in chartSeries
+// order, receiving the same cash flows.
+func writeTransactionReport(w io.Writer, track ActualTrack, strategies [][]float64, txCount int) {
+	final := track.Values[len(track.Values)-1]
+	fmt.Fprintf(w, "Transactions: %d trades, invested %.2f, withdrawn %.2f, actual portfolio %.2f\n", txCount, track.Invested, track.Withdrawn, final)
+	if track.Skipped > 0 {
+		fmt.Fprintf(w, "Transactions: %d trades after the last period were ignored\n", track.Skipped)
+	}
+	fmt.Fprintf(w, "%-14s %12s %12s\n", "Strategy", "Final", "Actual gap")
+	for j, cs := range chartSeries {
+		v := flowPath(strategies[j], track.Flows)
+		fmt.Fprintf(w, "%-14s %12.2f %12.2f\n", cs.Name, v, final-v)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// uploadEncodings are the Content-Encoding values of compressed outputs.
+var uploadEncodings = map[string]string{".gz": "gzip", ".zst": "zstd"}
+
+// uploadHeaders returns the Content-Type and Content-Encoding of an uploaded file. A
+// compressed output such as report.csv.gz keeps the type of the file inside and declares the
+// compression as its encoding; without an inner extension it is uploaded as an archive.
+func uploadHeaders(path string) (contentType string, encoding string) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if enc, ok := uploadEncodings[ext]; ok {
+		inner := strings.TrimSuffix(path, filepath.Ext(path))
+		if filepath.Ext(inner) == "" {
+			return "application/" + enc, ""
+		}
+		return uploadContentType(inner), enc
+	}
+	return uploadContentType(path), ""
+}
+
+func uploadContentType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "text/csv; charset=utf-8"
+	case ".tex":
+		return "application/x-tex"
+	}
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func validateUploadDest(dest string) error {
+	if dest == "" || strings.HasPrefix(dest, "s3://") || strings.HasPrefix(dest, "gs://") {
+		return nil
+	}
+	return fmt.Errorf("unsupported upload destination %q (use s3://bucket/prefix/ or gs://bucket/prefix/)", dest)
+}
+
+// uploadReports copies the generated files to s3:// or gs:// destinations using
+// the aws and gsutil command line tools, which carry their own credentials.
+func uploadReports(dest string, paths []string) error {
+	if err := validateUploadDest(dest); err != nil {
+		return err
+	}
+	scheme := dest[:2]
+	if !strings.HasSuffix(dest, "/") {
+		dest += "/"
+	}
+
+	for _, p := range paths {
+		target := dest + filepath.Base(p)
+		contentType, encoding := uploadHeaders(p)
+
+		var cmd *exec.Cmd
+		if scheme == "s3" {
+			args := []string{"s3", "cp", p, target, "--content-type", contentType}
+			if encoding != "" {
+				args = append(args, "--content-encoding", encoding)
+			}
+			cmd = exec.Command("aws", args...)
+		} else {
+			args := []string{"-h", "Content-Type:" + contentType}
+			if encoding != "" {
+				args = append(args, "-h", "Content-Encoding:"+encoding)
+			}
+			cmd = exec.Command("gsutil", append(args, "cp", p, target)...)
+		}
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("upload %s to %s: %w", p, target, err)
+		}
+		fmt.Fprintf(os.Stderr, "Uploaded %s (%s)\n", target, contentType)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestUploadHeaders(t *testing.T) {
+	tests := []struct {
+		path     string
+		typ      string
+		encoding string
+	}{
+		{"out/report.csv", "text/csv; charset=utf-8", ""},
+		{"out/report.csv.gz", "text/csv; charset=utf-8", "gzip"},
+		{"out/report.CSV.ZST", "text/csv; charset=utf-8", "zstd"},
+		{"out/tables.tex", "application/x-tex", ""},
+		{"out/archive.gz", "application/gzip", ""},
+	}
+	for _, tt := range tests {
+		typ, encoding := uploadHeaders(tt.path)
+		if typ != tt.typ || encoding != tt.encoding {
+			t.Errorf("uploadHeaders(%q) = %q, %q, want %q, %q", tt.path, typ, encoding, tt.typ, tt.encoding)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseViews parses -opt-views: comma separated SYMBOL=RETURN pairs of expected annual
+// returns, keyed by the symbol's position in symbols.
+func parseViews(value string, symbols []string) (map[int]float64, error) {
+	views := make(map[int]float64)
+	for _, pair := range strings.Split(value, ",") {
+		sym, ret, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid view %q (want SYMBOL=RETURN)", pair)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(ret), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid view return %q", ret)
+		}
+		found := false
+		for i, s := range symbols {
+			if strings.EqualFold(s, strings.TrimSpace(sym)) {
+				views[i] = v
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("view on %q, which is not among the optimized symbols %s", sym, strings.Join(symbols, ", "))
+		}
+	}
+	return views, nil
+}
+
+// blackLitterman blends the historical means mu with absolute views on single assets (per
+// period) in the Black-Litterman way: the prior is the historical mean with the uncertainty
+// of a sample mean, cov/T, and each view's variance is scaled so that confidence 1 imposes
+// it exactly and confidence near 0 ignores it. Views move correlated assets too.
+func blackLitterman(mu []float64, cov [][]float64, periods int, views map[int]float64, confidence float64) ([]float64, error) {
+	tau := 1 / float64(periods)
+	assets := make([]int, 0, len(views))
+	for i := range mu {
+		if _, ok := views[i]; ok {
+			assets = append(assets, i)
+		}
+	}
+	// Solve (P τΣ P' + Ω) x = Q − P μ for the view adjustments.
+	k := len(assets)
+	m := make([][]float64, k)
+	for a, i := range assets {
+		m[a] = make([]float64, k+1)
+		for b, j := range assets {
+			m[a][b] = tau * cov[i][j]
+		}
+		m[a][a] += tau * cov[i][i] * (1 - confidence) / confidence
+		m[a][k] = views[i] - mu[i]
+	}
+	if err := solveLinear(m, 1e-18); err != nil {
+		return nil, fmt.Errorf("views are on perfectly correlated assets")
+	}
+	// The posterior mean is μ + τΣ P' x.
+	out := append([]float64(nil), mu...)
+	for i := range out {
+		for a, j := range assets {
+			out[i] += tau * cov[i][j] * m[a][k]
+		}
+	}
+	return out, nil
+}
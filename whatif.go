@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// whatIfHTML renders the ETF weight slider shown under the cumulative chart.
+func whatIfHTML(weight float64) string {
+	pct := int(weight*100 + 0.5)
+	return fmt.Sprintf("<div class=\"meta\"><label>What-if ETF weight <input type=\"range\" id=\"whatIf\" min=\"0\" max=\"100\" step=\"1\" value=\"%d\"></label> <span id=\"whatIfValue\">%d%%</span></div>\n", pct, pct)
+}
+
+// whatIfJS embeds the period returns and adds a What-if line to cumChart that the slider
+// recomputes in the browser as a blend rebalanced every period.
+func whatIfJS(rows []ReportRow, weight float64) string {
+	var etf, idx strings.Builder
+	for i, r := range rows {
+		if i > 0 {
+			etf.WriteString(",")
+			idx.WriteString(",")
+		}
+		_, _ = fmt.Fprintf(&etf, "%.6f", r.ETFReturn)
+		_, _ = fmt.Fprintf(&idx, "%.6f", r.IndexReturn)
+	}
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "const etfRets = [%s];\nconst indexRets = [%s];\n", etf.String(), idx.String())
+	b.WriteString("function whatIfData(w){let v=100;return etfRets.map((r,i)=>{v*=1+w*r+(1-w)*indexRets[i];return Math.round(v*100)/100;});}\n")
+	_, _ = fmt.Fprintf(&b, "cumChart.data.datasets.push({label:'What-if %d%% ETF',data:whatIfData(%.2f),borderColor:'#333',borderDash:[2,2],pointRadius:0,tension:0.2});\ncumChart.update();\n", int(weight*100+0.5), weight)
+	b.WriteString("document.getElementById('whatIf').addEventListener('input',e=>{const ds=cumChart.data.datasets[cumChart.data.datasets.length-1];")
+	b.WriteString("ds.data=whatIfData(e.target.value/100);ds.label='What-if '+e.target.value+'% ETF';")
+	b.WriteString("document.getElementById('whatIfValue').textContent=e.target.value+'%';cumChart.update('none');});\n")
+	return b.String()
+}
+
+// glideEditorHTML renders the canvas of the glide path editor.
+func glideEditorHTML(height int) string {
+	return fmt.Sprintf("<h2>Glide path editor</h2>\n<div class=\"meta\">Drag the points to change the ETF weight; the GlidePath line above is recomputed in the browser.</div>\n<canvas id=\"glideEditor\" height=\"%d\"></canvas>\n", height)
+}
+
+// glideEditorJS draws the glide path as draggable points sampled evenly from weights and
+// redraws the GlidePath line of cumChart from etfRets and indexRets (see whatIfJS) when one
+// moves, interpolating linearly between the points.
+func glideEditorJS(rows []ReportRow, weights []float64, count int, color string) string {
+	var pos, vals strings.Builder
+	for k := 0; k < count; k++ {
+		i := int(float64(k)*float64(len(rows)-1)/float64(count-1) + 0.5)
+		if k > 0 {
+			pos.WriteString(",")
+			vals.WriteString(",")
+		}
+		_, _ = fmt.Fprintf(&pos, "%d", i)
+		_, _ = fmt.Fprintf(&vals, "%.1f", weights[i]*100)
+	}
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "const glidePos = [%s];\nconst glidePts = [%s];\n", pos.String(), vals.String())
+	b.WriteString("function glideTrack(){let v=100,k=0;return etfRets.map((r,i)=>{while(k<glidePos.length-2&&i>glidePos[k+1])k++;")
+	b.WriteString("const span=glidePos[k+1]-glidePos[k],t=span>0?Math.min(1,Math.max(0,(i-glidePos[k])/span)):0;")
+	b.WriteString("const w=(glidePts[k]+t*(glidePts[k+1]-glidePts[k]))/100;v*=1+w*r+(1-w)*indexRets[i];return Math.round(v*100)/100;});}\n")
+	b.WriteString("const glideEditor = new Chart(document.getElementById('glideEditor'),{type:'line',data:{labels:glidePos.map(i=>labels[i]),")
+	_, _ = fmt.Fprintf(&b, "datasets:[{label:'GlidePath ETF weight (%%)',data:glidePts,borderColor:'%s',pointRadius:7,pointHoverRadius:9}]},", color)
+	b.WriteString("options:{animation:false,plugins:{legend:{position:'bottom'}},scales:{y:{min:0,max:100,title:{display:true,text:'ETF weight (%)'}}}}});\n")
+	b.WriteString("let glideDrag=-1;const glideCanvas=document.getElementById('glideEditor');\n")
+	b.WriteString("glideCanvas.addEventListener('pointerdown',e=>{const hit=glideEditor.getElementsAtEventForMode(e,'nearest',{intersect:false},false);if(hit.length)glideDrag=hit[0].index;});\n")
+	b.WriteString("glideCanvas.addEventListener('pointermove',e=>{if(glideDrag<0)return;")
+	b.WriteString("glidePts[glideDrag]=Math.round(Math.min(100,Math.max(0,glideEditor.scales.y.getValueForPixel(e.offsetY))));glideEditor.update('none');")
+	b.WriteString("const ds=cumChart.data.datasets.find(d=>d.label==='GlidePath');if(ds){ds.data=glideTrack();cumChart.update('none');}});\n")
+	b.WriteString("['pointerup','pointerleave'].forEach(ev=>glideCanvas.addEventListener(ev,()=>{glideDrag=-1;}));\n")
+	return b.String()
+}
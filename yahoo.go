@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	yahoofinanceapi "github.com/oscarli916/yahoo-finance-api"
+)
+
+var yahooHTTP = &http.Client{Timeout: 30 * time.Second}
+
+// yahooTickers keeps one Ticker per symbol for the whole run. The library already shares a
+// single HTTP client, cookie and crumb between tickers, so reusing them avoids building a
+// new history/option client per call (splices, peers and yield comparisons load the same
+// symbol more than once).
+var yahooTickers = make(map[string]*yahoofinanceapi.Ticker)
+
+func yahooTicker(symbol string) *yahoofinanceapi.Ticker {
+	t, ok := yahooTickers[symbol]
+	if !ok {
+		t = yahoofinanceapi.NewTicker(symbol)
+		yahooTickers[symbol] = t
+	}
+	return t
+}
+
+// getYahooJSON fetches a Yahoo endpoint the history library does not wrap and decodes
+// the JSON body into out.
+func getYahooJSON(path string, params url.Values, out any) error {
+	endpoint := yahoofinanceapi.BASE_URL + path
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+	if err := providerBreaker.Allow(); err != nil {
+		return err
+	}
+	providerLimiter.Wait()
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		providerBreaker.Record(err)
+		return err
+	}
+	req.Header.Set("User-Agent", yahoofinanceapi.USER_AGENTS[0])
+
+	resp, err := yahooHTTP.Do(req)
+	if err != nil {
+		providerBreaker.Record(err)
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		err := fmt.Errorf("status %s: %w", resp.Status, ErrRateLimited)
+		providerBreaker.Record(err)
+		return err
+	}
+	if resp.StatusCode >= 500 {
+		err := fmt.Errorf("status %s", resp.Status)
+		providerBreaker.Record(err)
+		return err
+	}
+	providerBreaker.Record(nil)
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("status %s: %w", resp.Status, ErrSymbolNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}